@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/jroimartin/gocui"
+)
+
+// annotationHeight is the height of the comment pane below the move history.
+const annotationHeight = 12
+
+// annotationPaneHeight returns the height of the comment pane on a terminal
+// with maxY rows. It takes at most a third of the rows, and is 0 if it would be
+// too small to show a line, so the move history keeps its room.
+func annotationPaneHeight(maxY int) int {
+	height := min(annotationHeight, maxY/3)
+	if height < 3 {
+		return 0
+	}
+	return height
+}
+
+// selectedPly returns the ply shown on the board: the browsed history position
+// or the last move.
+func selectedPly() int {
+	if historyIndex >= 0 {
+		return historyIndex
+	}
+	return len(history.GetHistory()) - 1
+}
+
+// layoutAnnotations shows the comment, NAGs and commands of the selected ply.
+func layoutAnnotations(g *gocui.Gui, x0, y0, x1, y1 int) error {
+	v, err := g.SetView("annotation", x0, y0, x1, y1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Comments"
+		v.Wrap = true
+	}
+	v.Clear()
+	ply := selectedPly()
+	record, ok := history.GetRecord(ply)
+	if !ok {
+		return nil
+	}
+	number, black := history.MoveNumber(ply)
+	dots := "."
+	if black {
+		dots = "..."
+	}
+	fmt.Fprintf(v, "%d%s %s%s\n", number, dots, record.Format(history.GetNotation()), record.Glyphs())
+	if record.Eval != "" {
+		fmt.Fprintf(v, "Eval: %s\n", record.Eval)
+	}
+	if record.Clock != "" {
+		fmt.Fprintf(v, "Clock: %s\n", record.Clock)
+	}
+	if len(record.Arrows) > 0 {
+		fmt.Fprintf(v, "Arrows: %s\n", strings.Join(record.Arrows, " "))
+	}
+	if len(record.Squares) > 0 {
+		fmt.Fprintf(v, "Squares: %s\n", strings.Join(record.Squares, " "))
+	}
+	if record.Comment != "" {
+		fmt.Fprintf(v, "\n%s\n", record.Comment)
+	}
+	return nil
+}
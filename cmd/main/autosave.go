@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/RubikNube/TerminalChess/pkg/engine"
+	"github.com/RubikNube/TerminalChess/pkg/gui"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/jroimartin/gocui"
+)
+
+// savedGame is the state of the current game written to the autosave file.
+// Clock times are kept in the [%clk] commands of the annotations.
+type savedGame struct {
+	StartFEN    string           `json:"startFEN,omitempty"`
+	Chess960    bool             `json:"chess960,omitempty"`
+	Moves       []string         `json:"moves"`
+	Annotations []pgn.Annotation `json:"annotations,omitempty"`
+	Tags        []pgn.Tag        `json:"tags"`
+	Flipped     bool             `json:"flipped"`
+	EngineColor string           `json:"engineColor"`
+	Difficulty  string           `json:"difficulty,omitempty"`
+}
+
+var (
+	autosavePath     = filepath.Join("saves", "autosave.json")
+	autosaveEnabled  bool   // Set once the resume prompt is answered, so the saved game is not overwritten before
+	lastAutosave     []byte // Content of the last write, to skip unchanged states
+	resumeGame       *savedGame
+	showResumePrompt bool
+
+	noResumeFlag = flag.Bool("no-resume", false, "start a new game instead of offering to resume the autosaved one")
+)
+
+// currentGame returns the state of the current game.
+func currentGame() savedGame {
+	s := savedGame{
+		StartFEN:    history.GetStartPosition(),
+		Chess960:    gui.Chess960,
+		Moves:       []string{},
+		Tags:        pgn.SetTag(append([]pgn.Tag(nil), gameTags...), "Result", gameResult()),
+		Flipped:     gui.BoardFlipped,
+		EngineColor: engine.LoadedEngineConfig.EngineColor,
+	}
+	records := history.GetRecords()
+	annotations := make([]pgn.Annotation, len(records))
+	for ply, record := range records {
+		s.Moves = append(s.Moves, record.UCI)
+		annotations[ply] = record.Annotation
+		if !record.IsEmpty() {
+			// Only written if at least one move is annotated
+			s.Annotations = annotations
+		}
+	}
+	if level, ok := engine.CurrentDifficulty(); ok {
+		s.Difficulty = level.Name
+	}
+	return s
+}
+
+// finished reports whether the saved game has a result.
+func (s *savedGame) finished() bool {
+	result := pgn.TagValue(s.Tags, "Result")
+	return result != "" && result != "*"
+}
+
+// autosave writes the current game to the autosave file if it changed since
+// the last write. It is called wherever the game changes: moves, new and
+// loaded games, tag edits and engine settings.
+func autosave() {
+	if !autosaveEnabled {
+		return
+	}
+	data, err := json.MarshalIndent(currentGame(), "", "  ")
+	if err != nil || bytes.Equal(data, lastAutosave) {
+		return
+	}
+	if err := writeSavedGame(autosavePath, data); err != nil {
+		log.Printf("Failed to autosave game: %v", err)
+		return
+	}
+	lastAutosave = data
+}
+
+// writeSavedGame replaces the file at path through a temporary file, so a
+// crash never leaves a partly written state behind.
+func writeSavedGame(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadSavedGame reads a game written by autosave.
+func loadSavedGame(path string) (*savedGame, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s savedGame
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// restoreGame replaces the current game with a saved one.
+func restoreGame(s *savedGame) error {
+	gui.UseStandardCastling()
+	if s.Chess960 {
+		if err := gui.RestoreChess960(s.StartFEN); err != nil {
+			return err
+		}
+	}
+	engine.SetOption("UCI_Chess960", s.Chess960)
+	history.ClearHistory()
+	history.SetStartPosition(s.StartFEN, s.Chess960)
+	for _, move := range s.Moves {
+		history.AddMove(move)
+	}
+	for ply, a := range s.Annotations {
+		history.SetAnnotation(ply, a)
+	}
+	fen := history.GetPositionFEN(len(s.Moves) - 1)
+	board = gui.NewChessBoardFromFEN(fen)
+	gui.SetEnPassantSquareFromFEN(fen)
+	turn = sideToMove(fen)
+	historyIndex = -1
+	gameTags = s.Tags
+	gui.BoardFlipped = s.Flipped
+	if s.EngineColor != "" {
+		engine.LoadedEngineConfig.EngineColor = s.EngineColor
+	}
+	if s.Difficulty != "" {
+		if err := engine.SetDifficulty(s.Difficulty); err != nil {
+			log.Printf("Failed to restore difficulty: %v", err)
+		}
+	}
+	return nil
+}
+
+// offerResume shows the resume prompt if an unfinished game was autosaved.
+// Games with a result, such as checkmate or resignation, start a new game.
+func offerResume(g *gocui.Gui) {
+	if *noResumeFlag {
+		autosaveEnabled = true
+		return
+	}
+	s, err := loadSavedGame(autosavePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read autosaved game: %v", err)
+		}
+		autosaveEnabled = true
+		return
+	}
+	if (len(s.Moves) == 0 && s.StartFEN == "") || s.finished() {
+		autosaveEnabled = true
+		return
+	}
+	resumeGame = s
+	showResumePrompt = true
+	g.DeleteKeybindings("")
+	bindContext(g, resumePromptContext(), nil)
+}
+
+func resumePromptContext() keyContext {
+	return keyContext{name: "Resume prompt", view: "resume", bindings: []binding{
+		{keys: []string{"y", "Enter"}, help: "resume the last game", handler: answerResume(true)},
+		{keys: []string{"n", "Esc"}, help: "start a new game", handler: answerResume(false)},
+	}}
+}
+
+// layoutResumePrompt asks whether to resume the autosaved game.
+func layoutResumePrompt(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	const width = 50
+	x0, y0 := max((maxX-width)/2, 0), max(maxY/2-2, 0)
+	if v, err := g.SetView("resume", x0, y0, x0+width, y0+4); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Resume game"
+		fmt.Fprintf(v, " %s vs %s, %d moves\n",
+			pgn.TagValue(resumeGame.Tags, "White"), pgn.TagValue(resumeGame.Tags, "Black"), (len(resumeGame.Moves)+1)/2)
+		fmt.Fprintln(v, " Resume the last game? [y/n]")
+		g.SetCurrentView("resume")
+	}
+	return nil
+}
+
+func answerResume(resume bool) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if resume {
+			if err := restoreGame(resumeGame); err != nil {
+				log.Printf("Failed to resume game: %v", err)
+				showInfoMessage(g, "Failed to resume the last game.")
+			} else {
+				showInfoMessage(g, "Resumed the last game.")
+			}
+		}
+		showResumePrompt = false
+		resumeGame = nil
+		autosaveEnabled = true
+		autosave()
+		g.DeleteKeybindings("resume")
+		g.DeleteView("resume")
+		g.SetCurrentView("board")
+		enableGlobalKeybindings(g, cfg.Keybindings)
+		return nil
+	}
+}
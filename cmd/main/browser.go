@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/RubikNube/TerminalChess/pkg/text"
+	"github.com/jroimartin/gocui"
+)
+
+var (
+	showGameBrowser bool
+	browserSource   string         // File the listed games were read from
+	browserHint     string         // Title of the filter line
+	browserGames    []pgn.GameInfo // All games of the file
+	browserMatches  []pgn.GameInfo // Games matching the current filter
+	browserIndex    int            // Selected row in browserMatches
+	browserError    error          // Error of the current filter, if any
+	browserSearch   func(query string) ([]pgn.GameInfo, error)
+)
+
+// openGameBrowser shows the list of games read from source.
+func openGameBrowser(g *gocui.Gui, games []pgn.GameInfo, source string) error {
+	hint := fmt.Sprintf("Filter games in %s (e.g. carlsen, white:carlsen eco:B90)", filepath.Base(source))
+	search := func(query string) ([]pgn.GameInfo, error) {
+		return pgn.Filter(games, query), nil
+	}
+	return openBrowser(g, games, source, hint, search)
+}
+
+// openBrowser shows games with a filter line that selects them using search.
+func openBrowser(g *gocui.Gui, games []pgn.GameInfo, source, hint string, search func(string) ([]pgn.GameInfo, error)) error {
+	browserSource = source
+	browserHint = hint
+	browserGames = games
+	browserMatches = games
+	browserIndex = 0
+	browserError = nil
+	browserSearch = search
+	showGameBrowser = true
+	enableGameBrowserKeybindings(g)
+	return layout(g)
+}
+
+func closeGameBrowser(g *gocui.Gui) {
+	showGameBrowser = false
+	g.DeleteView("browserFilter")
+	g.DeleteView("browser")
+	g.Cursor = false
+	g.SetCurrentView("board")
+	enableGlobalKeybindings(g, cfg.Keybindings)
+}
+
+// layoutGameBrowser draws the filter input and the game list on top of the board.
+func layoutGameBrowser(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	x0, y0, x1 := 2, 1, maxX-3
+	if v, err := g.SetView("browserFilter", x0, y0, x1, y0+2); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = browserHint
+		v.Editable = true
+		v.Editor = gocui.EditorFunc(browserFilterEditor)
+		g.SetCurrentView("browserFilter")
+		g.Cursor = true
+	}
+	v, err := g.SetView("browser", x0, y0+3, x1, maxY-2)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Highlight = true
+		v.SelBgColor = gocui.ColorGreen
+		v.SelFgColor = gocui.ColorBlack
+	}
+	v.Title = fmt.Sprintf("%d of %d games - Enter: load, Esc: cancel", len(browserMatches), len(browserGames))
+	if browserError != nil {
+		v.Title = fmt.Sprintf("Invalid filter: %v", browserError)
+	}
+	v.Clear()
+	fmt.Fprintln(v, formatGameRow("#", "White", "Black", "Result", "Date", "Event", "ECO", "Moves"))
+	for _, gi := range browserMatches {
+		fmt.Fprintln(v, formatGameRow(
+			fmt.Sprintf("%d", gi.Index+1), gi.Tag("White"), gi.Tag("Black"), gi.Tag("Result"),
+			gi.Tag("Date"), gi.Tag("Event"), gi.Tag("ECO"), fmt.Sprintf("%d", (gi.Moves+1)/2)))
+	}
+
+	// Keep the selected row (below the header line) visible
+	_, height := v.Size()
+	_, oy := v.Origin()
+	row := browserIndex + 1
+	if row < oy+1 {
+		oy = row - 1
+	} else if row >= oy+height {
+		oy = row - height + 1
+	}
+	v.SetOrigin(0, oy)
+	v.SetCursor(0, row-oy)
+	return nil
+}
+
+// formatGameRow lays out one row of the game list in fixed width columns.
+func formatGameRow(index, white, black, result, date, event, eco, moves string) string {
+	return fmt.Sprintf("%5s  %-22s %-22s %-7s %-10s %-24s %-3s %5s",
+		index, text.Truncate(white, 22), text.Truncate(black, 22), result, text.Truncate(date, 10), text.Truncate(event, 24), eco, moves)
+}
+
+// browserFilterEditor edits the filter line and updates the list on every change.
+func browserFilterEditor(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	// Keep the filter on a single line
+	if key == gocui.KeyEnter {
+		return
+	}
+	gocui.DefaultEditor.Edit(v, key, ch, mod)
+	updateBrowserMatches(v)
+}
+
+// updateBrowserMatches searches the games with the query of the filter line.
+func updateBrowserMatches(v *gocui.View) {
+	browserMatches, browserError = browserSearch(strings.TrimSpace(v.Buffer()))
+	browserIndex = 0
+}
+
+// moveBrowserSelection returns a handler moving the selected game by delta rows.
+func moveBrowserSelection(delta int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		browserIndex += delta
+		if browserIndex >= len(browserMatches) {
+			browserIndex = len(browserMatches) - 1
+		}
+		if browserIndex < 0 {
+			browserIndex = 0
+		}
+		return nil
+	}
+}
+
+// loadSelectedGame loads the selected game of the browser.
+func loadSelectedGame(g *gocui.Gui, v *gocui.View) error {
+	if len(browserMatches) == 0 {
+		showInfoMessage(g, "No game selected.")
+		return nil
+	}
+	selectedGame := browserMatches[browserIndex]
+	source := fmt.Sprintf("%s (game %d)", browserSource, selectedGame.Index+1)
+	if !loadPGNGame(g, selectedGame.Raw, source) {
+		return nil
+	}
+	closeGameBrowser(g)
+	return layout(g)
+}
+
+func cancelGameBrowser(g *gocui.Gui, v *gocui.View) error {
+	closeGameBrowser(g)
+	return layout(g)
+}
+
+func gameBrowserContext() keyContext {
+	return keyContext{name: "Game browser", view: "browserFilter", bindings: []binding{
+		{keys: []string{"Enter"}, help: "load the selected game", handler: loadSelectedGame},
+		{keys: []string{"Up", "Ctrl+y"}, help: "select the previous game", handler: moveBrowserSelection(-1)},
+		{keys: []string{"Down", "Ctrl+x"}, help: "select the next game", handler: moveBrowserSelection(1)},
+		{keys: []string{"PgUp"}, help: "move the selection up a page", handler: moveBrowserSelection(-10)},
+		{keys: []string{"PgDn"}, help: "move the selection down a page", handler: moveBrowserSelection(10)},
+		{keys: []string{"Esc", "Ctrl+q"}, help: "cancel", handler: cancelGameBrowser},
+	}}
+}
+
+func enableGameBrowserKeybindings(g *gocui.Gui) {
+	g.DeleteKeybindings("")
+	g.DeleteKeybindings("browserFilter")
+	bindContext(g, gameBrowserContext(), nil)
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/engine"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/corentings/chess"
+	"github.com/jroimartin/gocui"
+)
+
+var (
+	showCommandLine     bool
+	commandHistory      []string // Executed command lines, oldest first
+	commandHistoryIndex int      // Position while browsing commandHistory, len(commandHistory) for a new line
+)
+
+// command is a command of the ex-style command line.
+type command struct {
+	usage string // Arguments, shown by :help
+	help  string
+	// complete returns the candidates for the argument at index arg, nil for file paths
+	complete func(arg int) []string
+	run      func(g *gocui.Gui, args []string) error
+}
+
+// commands maps command names to commands. It is filled in init, as the help
+// command refers to it.
+var commands map[string]command
+
+// noArgs wraps a key handler as a command without arguments.
+func noArgs(handler func(*gocui.Gui, *gocui.View) error) func(*gocui.Gui, []string) error {
+	return func(g *gocui.Gui, args []string) error {
+		return handler(g, nil)
+	}
+}
+
+func init() {
+	commands = map[string]command{
+		"fen":      {usage: "<fen>", help: "start a game from a position", run: runFENCommand},
+		"load":     {usage: "<file>", help: "load a PGN file", run: runLoadCommand},
+		"save":     {usage: "[file]", help: "save the game as PGN", run: runSaveCommand},
+		"engine":   {usage: "depth|movetime|difficulty|option|color|automove <value>", help: "change engine settings", complete: completeEngineCommand, run: runEngineCommand},
+		"move":     {usage: "<move>", help: "play a move given in SAN or UCI", run: runMoveCommand},
+		"flip":     {help: "flip the board", run: noArgs(switchBoard)},
+		"pieces":   {usage: "[name]", help: "switch the piece set", complete: completePiecesCommand, run: runPiecesCommand},
+		"theme":    {usage: "[name]", help: "switch the colour theme", complete: completeThemeCommand, run: runThemeCommand},
+		"goto":     {usage: "<move>[...]|end", help: "show the position after a move, e.g. 23 or 23...", run: runGotoCommand},
+		"reset":    {help: "start a new game", run: noArgs(reset)},
+		"chess960": {usage: "[position]", help: "start a Chess960 game", run: runChess960Command},
+		"copy":     {help: "copy the game PGN to the clipboard", run: noArgs(copyPGNToClipboard)},
+		"paste":    {help: "paste a FEN or PGN from the clipboard", run: noArgs(pasteFromClipboard)},
+		"diagram":  {help: "export the shown position as PNG and SVG", run: noArgs(exportDiagram)},
+		"gif":      {help: "export the game as an animated GIF", run: noArgs(exportGIF)},
+		"tags":     {help: "edit the PGN tags", run: noArgs(openTagEditor)},
+		"info":     {help: "show/hide the game info", run: noArgs(toggleMetadata)},
+		"history":  {help: "show/hide the move history", run: noArgs(toggleHistory)},
+		"search":   {help: "search the game database", run: noArgs(openDatabaseSearch)},
+		"quit":     {help: "quit the game", run: noArgs(quit)},
+		"help":     {usage: "[command]", help: "list the commands", complete: func(int) []string { return commandNames() }, run: runHelpCommand},
+	}
+}
+
+// commandNames returns the sorted names of all commands.
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openCommandLine shows the command line below the board.
+func openCommandLine(g *gocui.Gui, v *gocui.View) error {
+	showCommandLine = true
+	commandHistoryIndex = len(commandHistory)
+	cycleMatches = nil
+	enableCommandLineKeybindings(g)
+	return layout(g)
+}
+
+func closeCommandLine(g *gocui.Gui) {
+	showCommandLine = false
+	g.DeleteView("command")
+	g.Cursor = false
+	g.SetCurrentView("board")
+	enableGlobalKeybindings(g, cfg.Keybindings)
+}
+
+// layoutCommandLine draws the command line over the InfoView, which ends at x1.
+func layoutCommandLine(g *gocui.Gui, x1 int) error {
+	_, maxY := g.Size()
+	v, err := g.SetView("command", 0, maxY-3, x1, maxY-1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Command - Enter: run, Tab: complete, Up/Down: history, Esc: cancel"
+		v.Editable = true
+		v.Editor = gocui.EditorFunc(commandLineEditor)
+		g.SetCurrentView("command")
+		g.Cursor = true
+	}
+	return nil
+}
+
+// commandLineEditor edits the single command line. Typing resets the completion.
+func commandLineEditor(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	if key == gocui.KeyEnter {
+		return
+	}
+	cycleMatches = nil
+	gocui.DefaultEditor.Edit(v, key, ch, mod)
+}
+
+// setCommandLine replaces the text of the command line.
+func setCommandLine(v *gocui.View, text string) {
+	v.Clear()
+	fmt.Fprint(v, text)
+	v.SetOrigin(0, 0)
+	v.SetCursor(len([]rune(text)), 0)
+}
+
+// runCommandLine closes the command line and runs its command.
+func runCommandLine(g *gocui.Gui, v *gocui.View) error {
+	line := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(v.Buffer()), ":"))
+	closeCommandLine(g)
+	if line == "" {
+		return nil
+	}
+	if len(commandHistory) == 0 || commandHistory[len(commandHistory)-1] != line {
+		commandHistory = append(commandHistory, line)
+	}
+	return executeCommand(g, line)
+}
+
+// executeCommand runs a command line. Mistakes are reported in the InfoView,
+// only gocui.ErrQuit and layout errors are returned.
+func executeCommand(g *gocui.Gui, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	name, args := fields[0], fields[1:]
+	cmd, ok := commands[name]
+	if !ok {
+		// Unique prefixes are accepted, e.g. :fl for :flip
+		var matches []string
+		for _, n := range commandNames() {
+			if strings.HasPrefix(n, name) {
+				matches = append(matches, n)
+			}
+		}
+		if len(matches) != 1 {
+			showInfoMessage(g, fmt.Sprintf("Unknown command: %s (see :help)", name))
+			return nil
+		}
+		cmd = commands[matches[0]]
+	}
+	return cmd.run(g, args)
+}
+
+// moveInCommandHistory returns a handler showing the previous (-1) or next (1)
+// command of the history.
+func moveInCommandHistory(step int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		index := commandHistoryIndex + step
+		if index < 0 || index > len(commandHistory) {
+			return nil
+		}
+		commandHistoryIndex = index
+		text := ""
+		if index < len(commandHistory) {
+			text = commandHistory[index]
+		}
+		setCommandLine(v, text)
+		return nil
+	}
+}
+
+// completeCommandLine completes the word before the cursor: a command name,
+// an argument of the command or a file path.
+func completeCommandLine(step int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		line := strings.TrimPrefix(strings.TrimRight(v.Buffer(), "\n"), ":")
+		fields := strings.Fields(line)
+		if strings.HasSuffix(line, " ") || len(fields) == 0 {
+			fields = append(fields, "")
+		}
+		word := fields[len(fields)-1]
+		prefix := strings.TrimSuffix(line, word)
+
+		if len(fields) == 1 {
+			if match, ok := nextCompletion(g, func() []string { return withPrefix(commandNames(), word) }, step); ok {
+				word = match
+			}
+		} else if cmd, ok := commands[fields[0]]; ok && cmd.complete != nil {
+			arg := len(fields) - 2
+			if match, ok := nextCompletion(g, func() []string { return withPrefix(cmd.complete(arg), word) }, step); ok {
+				word = match
+			}
+		} else {
+			word = completeFilePath(g, word, step)
+		}
+		setCommandLine(v, prefix+word)
+		return nil
+	}
+}
+
+// withPrefix returns the candidates starting with prefix.
+func withPrefix(candidates []string, prefix string) []string {
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+func runFENCommand(g *gocui.Gui, args []string) error {
+	fen := strings.Join(args, " ")
+	if _, err := chess.FEN(fen); err != nil {
+		showInfoMessage(g, fmt.Sprintf("Invalid FEN: %v", err))
+		return nil
+	}
+	loadFEN(fen)
+	showInfoMessage(g, "Position set up from FEN.")
+	return nil
+}
+
+func runLoadCommand(g *gocui.Gui, args []string) error {
+	if len(args) == 0 {
+		showInfoMessage(g, "Usage: :load <file>")
+		return nil
+	}
+	path := strings.Join(args, " ")
+	games, err := readPGNFile(path)
+	if err != nil {
+		showInfoMessage(g, fmt.Sprintf("Failed to load game: %v", err))
+		return nil
+	}
+	if len(games) > 1 {
+		return openGameBrowser(g, games, path)
+	}
+	loadPGNGame(g, games[0].Raw, path)
+	return nil
+}
+
+func runSaveCommand(g *gocui.Gui, args []string) error {
+	if len(args) == 0 {
+		return saveGameAsPGN(g, nil)
+	}
+	path := strings.Join(args, " ")
+	if filepath.Ext(path) == "" {
+		path += ".pgn"
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			showInfoMessage(g, fmt.Sprintf("Error creating %s: %v", dir, err))
+			return nil
+		}
+	}
+	if err := writePGN(path); err != nil {
+		showInfoMessage(g, fmt.Sprintf("Error creating PGN file: %v", err))
+		return nil
+	}
+	showInfoMessage(g, fmt.Sprintf("Game saved to %s", path))
+	return nil
+}
+
+// completeEngineCommand completes the setting and its value for :engine.
+func completeEngineCommand(arg int) []string {
+	if arg == 0 {
+		return []string{"automove", "color", "depth", "difficulty", "movetime", "option"}
+	}
+	var names []string
+	for _, level := range engine.Difficulties() {
+		names = append(names, level.Name)
+	}
+	return append(names, "white", "black", "on", "off")
+}
+
+func runEngineCommand(g *gocui.Gui, args []string) error {
+	if len(args) < 2 {
+		depth, moveTime := engine.SearchLimits()
+		showInfoMessage(g, fmt.Sprintf("Engine: %s, depth %d, movetime %d ms, color %s, automove %v",
+			engine.PlayerName(), depth, moveTime, engine.LoadedEngineConfig.EngineColor, engine.LoadedEngineConfig.Automove))
+		return nil
+	}
+	setting, value := args[0], args[1]
+	depth, moveTime := engine.SearchLimits()
+	switch setting {
+	case "depth", "movetime":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			showInfoMessage(g, fmt.Sprintf("Invalid %s: %s", setting, value))
+			return nil
+		}
+		if setting == "depth" {
+			depth = n
+		} else {
+			moveTime = n
+		}
+		engine.SetSearchLimits(depth, moveTime)
+		showInfoMessage(g, fmt.Sprintf("Engine search limited to depth %d, movetime %d ms (0: configured)", depth, moveTime))
+	case "difficulty":
+		oldName := engine.PlayerName()
+		if err := engine.SetDifficulty(value); err != nil {
+			showInfoMessage(g, err.Error())
+			return nil
+		}
+		renamePlayer(oldName, engine.PlayerName())
+		autosave()
+		showInfoMessage(g, fmt.Sprintf("Difficulty: %s", engine.PlayerName()))
+	case "option":
+		engine.SetOption(value, strings.Join(args[2:], " "))
+		showInfoMessage(g, fmt.Sprintf("Engine option %s set to %s", value, strings.Join(args[2:], " ")))
+	case "color":
+		if value != "white" && value != "black" {
+			showInfoMessage(g, "Usage: :engine color white|black")
+			return nil
+		}
+		engine.LoadedEngineConfig.EngineColor = value
+		autosave()
+		showInfoMessage(g, fmt.Sprintf("Engine plays %s", value))
+	case "automove":
+		engine.LoadedEngineConfig.Automove = value == "on" || value == "true"
+		showInfoMessage(g, fmt.Sprintf("Engine automove: %v", engine.LoadedEngineConfig.Automove))
+	default:
+		showInfoMessage(g, fmt.Sprintf("Unknown engine setting: %s", setting))
+	}
+	return nil
+}
+
+// runMoveCommand plays a move given in SAN or UCI, e.g. ":move Nf3".
+func runMoveCommand(g *gocui.Gui, args []string) error {
+	if len(args) == 0 {
+		showInfoMessage(g, "Usage: :move <move>")
+		return nil
+	}
+	if err := playTypedMoveText(g, strings.Join(args, " ")); err != nil {
+		showInfoMessage(g, err.Error())
+	}
+	return nil
+}
+
+// runGotoCommand shows the position after White's move n ("23") or Black's
+// move n ("23..."), or the latest position ("end").
+func runGotoCommand(g *gocui.Gui, args []string) error {
+	moves := len(history.GetHistory())
+	if len(args) != 1 {
+		showInfoMessage(g, "Usage: :goto <move>[...]|end")
+		return nil
+	}
+	if args[0] == "end" {
+		historyIndex = -1
+		return nil
+	}
+	number := strings.TrimRight(args[0], ".")
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 {
+		showInfoMessage(g, fmt.Sprintf("Invalid move number: %s", args[0]))
+		return nil
+	}
+	ply := history.MovePly(n, number != args[0])
+	if ply < 0 || ply >= moves {
+		first, _ := history.MoveNumber(0)
+		last, _ := history.MoveNumber(max(moves-1, 0))
+		showInfoMessage(g, fmt.Sprintf("The game has only moves %d to %d.", first, last))
+		return nil
+	}
+	historyIndex = ply
+	if ply == moves-1 {
+		historyIndex = -1
+	}
+	return nil
+}
+
+func runChess960Command(g *gocui.Gui, args []string) error {
+	if len(args) == 0 {
+		return newChess960Game(g, nil)
+	}
+	sp, err := strconv.Atoi(args[0])
+	if err != nil {
+		showInfoMessage(g, fmt.Sprintf("Invalid start position: %s", args[0]))
+		return nil
+	}
+	if err := startChess960(sp); err != nil {
+		showInfoMessage(g, fmt.Sprintf("Failed to start Chess960 game: %v", err))
+		return nil
+	}
+	showInfoMessage(g, fmt.Sprintf("New Chess960 game, start position %d", sp))
+	return nil
+}
+
+func runHelpCommand(g *gocui.Gui, args []string) error {
+	if len(args) > 0 {
+		cmd, ok := commands[args[0]]
+		if !ok {
+			showInfoMessage(g, fmt.Sprintf("Unknown command: %s", args[0]))
+			return nil
+		}
+		showInfoMessage(g, strings.TrimSpace(fmt.Sprintf(":%s %s", args[0], cmd.usage))+" - "+cmd.help)
+		return nil
+	}
+	showInfoMessage(g, "Commands: "+strings.Join(commandNames(), " ")+" (:help <command> for details)")
+	return nil
+}
+
+func cancelCommandLine(g *gocui.Gui, v *gocui.View) error {
+	closeCommandLine(g)
+	return nil
+}
+
+func commandLineContext() keyContext {
+	return keyContext{name: "Command line", view: "command", bindings: []binding{
+		{keys: []string{"Enter"}, help: "run the command", handler: runCommandLine},
+		{keys: []string{"Tab", "Ctrl+x"}, help: "complete the command", handler: completeCommandLine(1)},
+		{keys: []string{"Ctrl+y"}, help: "previous completion", handler: completeCommandLine(-1)},
+		{keys: []string{"Up"}, help: "previous command", handler: moveInCommandHistory(-1)},
+		{keys: []string{"Down"}, help: "next command", handler: moveInCommandHistory(1)},
+		{keys: []string{"Esc", "Ctrl+q"}, help: "cancel", handler: cancelCommandLine},
+	}}
+}
+
+func enableCommandLineKeybindings(g *gocui.Gui) {
+	g.DeleteKeybindings("")
+	g.DeleteKeybindings("command")
+	bindContext(g, commandLineContext(), nil)
+}
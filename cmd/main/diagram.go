@@ -0,0 +1,227 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/RubikNube/TerminalChess/pkg/diagram"
+	"github.com/RubikNube/TerminalChess/pkg/gui"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/corentings/chess"
+	"github.com/jroimartin/gocui"
+)
+
+// moveSquares returns the from and to squares of a move in UCI notation.
+func moveSquares(uci string) []string {
+	if len(uci) < 4 {
+		return nil
+	}
+	return []string{uci[:2], uci[2:4]}
+}
+
+// parseArrows parses [%cal] style arrows and adds them to arrows. Invalid
+// arrows are skipped.
+func parseArrows(arrows []diagram.Arrow, specs []string) []diagram.Arrow {
+	for _, spec := range specs {
+		if a, err := diagram.ParseArrow(spec); err == nil {
+			arrows = append(arrows, a)
+		}
+	}
+	return arrows
+}
+
+// writeDiagram writes the position as PNG or SVG, depending on the extension of path.
+func writeDiagram(path, fen string, opts diagram.Options) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".svg") {
+		return diagram.WriteSVG(f, fen, opts)
+	}
+	return diagram.WritePNG(f, fen, opts)
+}
+
+// exportDiagram saves the position shown on the board as PNG and SVG diagrams
+// in the "saves" directory.
+func exportDiagram(g *gocui.Gui, v *gocui.View) error {
+	saveDir := "saves"
+	if err := os.MkdirAll(saveDir, 0755); err != nil {
+		showInfoMessage(g, fmt.Sprintf("Error creating saves directory: %v", err))
+		return nil
+	}
+	fen := board.ToFEN(turn)
+	if historyIndex >= 0 {
+		fen = history.GetPositionFEN(historyIndex)
+	}
+	opts := diagram.Options{Flipped: gui.BoardFlipped, Coordinates: true}
+	if record, ok := history.GetRecord(selectedPly()); ok {
+		opts.LastMove = moveSquares(record.UCI)
+		opts.Arrows = parseArrows(nil, record.Arrows)
+	}
+
+	base := filepath.Join(saveDir, "diagram_"+time.Now().Format("2006-01-02-15-04-05"))
+	for _, ext := range []string{".png", ".svg"} {
+		if err := writeDiagram(base+ext, fen, opts); err != nil {
+			showInfoMessage(g, fmt.Sprintf("Error writing diagram: %v", err))
+			return nil
+		}
+	}
+	showInfoMessage(g, fmt.Sprintf("Diagram saved to %s.png and .svg", base))
+	return nil
+}
+
+// exportGIF saves the moves of the game as an animated GIF in the "saves" directory.
+func exportGIF(g *gocui.Gui, v *gocui.View) error {
+	saveDir := "saves"
+	if err := os.MkdirAll(saveDir, 0755); err != nil {
+		showInfoMessage(g, fmt.Sprintf("Error creating saves directory: %v", err))
+		return nil
+	}
+	frames := []diagram.Frame{{FEN: history.GetPositionFEN(-1)}}
+	for _, record := range history.GetRecords() {
+		frames = append(frames, diagram.Frame{
+			FEN:      record.FEN,
+			LastMove: moveSquares(record.UCI),
+			Arrows:   parseArrows(nil, record.Arrows),
+		})
+	}
+	path := filepath.Join(saveDir, "game_"+time.Now().Format("2006-01-02-15-04-05")+".gif")
+	opts := diagram.GIFOptions{Options: diagram.Options{Flipped: gui.BoardFlipped, Coordinates: true}}
+	if err := writeGIF(path, frames, opts); err != nil {
+		showInfoMessage(g, fmt.Sprintf("Error writing GIF: %v", err))
+		return nil
+	}
+	showInfoMessage(g, fmt.Sprintf("Animation saved to %s", path))
+	return nil
+}
+
+func writeGIF(path string, frames []diagram.Frame, opts diagram.GIFOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return diagram.WriteGIF(f, frames, opts)
+}
+
+// runGIFCommand writes an animated GIF of a game of a PGN file:
+//
+//	terminalchess gif [options] -pgn game.pgn [-game n]
+func runGIFCommand(args []string) error {
+	fs := flag.NewFlagSet("gif", flag.ExitOnError)
+	pgnPath := fs.String("pgn", "", "PGN file with the game")
+	gameNumber := fs.Int("game", 1, "game of the PGN file, starting at 1")
+	output := fs.String("o", "game.gif", "output file")
+	delay := fs.Int("delay", 1000, "time each position is shown in milliseconds")
+	finalDelay := fs.Int("final-delay", 3000, "time the final position is shown in milliseconds")
+	size := fs.Int("size", 512, "board size in pixels")
+	flipped := fs.Bool("flip", false, "draw the board from Black's side")
+	coordinates := fs.Bool("coords", true, "draw file and rank labels")
+	fs.Parse(args)
+	if *pgnPath == "" {
+		fmt.Fprintln(fs.Output(), "Usage: terminalchess gif [options] -pgn file")
+		fs.PrintDefaults()
+		os.Exit(2)
+	}
+
+	frames, err := pgnFrames(*pgnPath, *gameNumber)
+	if err != nil {
+		return err
+	}
+	return writeGIF(*output, frames, diagram.GIFOptions{
+		Options:    diagram.Options{Size: *size, Flipped: *flipped, Coordinates: *coordinates},
+		Delay:      time.Duration(*delay) * time.Millisecond,
+		FinalDelay: time.Duration(*finalDelay) * time.Millisecond,
+	})
+}
+
+// runDiagramCommand writes a diagram of a FEN or of a position of a PGN game:
+//
+//	terminalchess diagram [options] -fen FEN
+//	terminalchess diagram [options] -pgn game.pgn [-game n] [-ply n]
+func runDiagramCommand(args []string) error {
+	fs := flag.NewFlagSet("diagram", flag.ExitOnError)
+	fen := fs.String("fen", "", "position to draw")
+	pgnPath := fs.String("pgn", "", "PGN file to take the position from")
+	gameNumber := fs.Int("game", 1, "game of the PGN file, starting at 1")
+	ply := fs.Int("ply", -1, "number of half moves played in the PGN game, all if not set")
+	output := fs.String("o", "diagram.png", "output file, .png or .svg")
+	size := fs.Int("size", 512, "board size in pixels")
+	flipped := fs.Bool("flip", false, "draw the board from Black's side")
+	coordinates := fs.Bool("coords", true, "draw file and rank labels")
+	arrows := fs.String("arrows", "", "comma separated arrows, e.g. Ge2e4,Rd8h4")
+	fs.Parse(args)
+	if (*fen == "") == (*pgnPath == "") {
+		fmt.Fprintln(fs.Output(), "Usage: terminalchess diagram [options] (-fen FEN | -pgn file)")
+		fs.PrintDefaults()
+		os.Exit(2)
+	}
+
+	opts := diagram.Options{Size: *size, Flipped: *flipped, Coordinates: *coordinates}
+	position := *fen
+	if *pgnPath != "" {
+		frames, err := pgnFrames(*pgnPath, *gameNumber)
+		if err != nil {
+			return err
+		}
+		if *ply < 0 || *ply >= len(frames) {
+			*ply = len(frames) - 1
+		}
+		frame := frames[*ply]
+		position, opts.LastMove, opts.Arrows = frame.FEN, frame.LastMove, frame.Arrows
+	}
+	if *arrows != "" {
+		for _, spec := range strings.Split(*arrows, ",") {
+			a, err := diagram.ParseArrow(strings.TrimSpace(spec))
+			if err != nil {
+				return err
+			}
+			opts.Arrows = append(opts.Arrows, a)
+		}
+	}
+	return writeDiagram(*output, position, opts)
+}
+
+// pgnFrames returns every position of a game in a PGN file, starting with
+// the initial position, with its last move and annotated arrows.
+func pgnFrames(path string, gameNumber int) ([]diagram.Frame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	games, err := pgn.ReadGames(f)
+	if err != nil {
+		return nil, err
+	}
+	if gameNumber < 1 || gameNumber > len(games) {
+		return nil, fmt.Errorf("%s has %d games", path, len(games))
+	}
+	raw := games[gameNumber-1].Raw
+	gameFunc, err := chess.PGN(strings.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PGN game: %w", err)
+	}
+	game := chess.NewGame(gameFunc)
+	moves, positions := game.Moves(), game.Positions()
+	_, annotated := pgn.ParseMainline(pgn.Movetext(raw))
+	frames := []diagram.Frame{{FEN: positions[0].String()}}
+	for i, move := range moves {
+		frame := diagram.Frame{
+			FEN:      positions[i+1].String(),
+			LastMove: moveSquares(chess.UCINotation{}.Encode(positions[i], move)),
+		}
+		if len(annotated) == len(moves) {
+			frame.Arrows = parseArrows(nil, annotated[i].Arrows)
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RubikNube/TerminalChess/pkg/engine"
+	"github.com/RubikNube/TerminalChess/pkg/epd"
+)
+
+// runEPDCommand runs the configured engine on an EPD test suite:
+//
+//	terminalchess epd [-depth n] [-movetime ms] [-format text|csv|json] suite.epd
+func runEPDCommand(args []string) error {
+	fs := flag.NewFlagSet("epd", flag.ExitOnError)
+	depth := fs.Int("depth", 0, "search depth per position")
+	moveTime := fs.Int("movetime", 1000, "search time per position in milliseconds")
+	format := fs.String("format", "text", "report format: text, csv or json")
+	engineConfig := fs.String("engine", "engine.json", "engine configuration file")
+	difficulty := fs.String("difficulty", "", "difficulty level, the configured level is not used")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminalchess epd [options] suite.epd")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	write := map[string]func(io.Writer, []epd.Result) error{
+		"text": epd.WriteText,
+		"csv":  epd.WriteCSV,
+		"json": epd.WriteJSON,
+	}[*format]
+	if write == nil {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	positions, err := epd.Read(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+
+	// Test suites measure the engine at full strength, unless asked otherwise
+	if err := engine.InitializeWithDifficulty(*engineConfig, *difficulty); err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	search := func(fen string) (string, error) {
+		return engine.GetBestMoveWithLimits(fen, *depth, *moveTime)
+	}
+	progress := func(r epd.Result) {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", r.ID, r.Move)
+	}
+	return write(os.Stdout, epd.Run(positions, search, progress))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/gamedb"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/jroimartin/gocui"
+)
+
+// defaultDatabasePath is used if config.json does not name a database file.
+const defaultDatabasePath = "saves/gamedb.json"
+
+// databasePath returns the configured game database file.
+func databasePath() string {
+	if cfg.Database.Path != "" {
+		return cfg.Database.Path
+	}
+	return defaultDatabasePath
+}
+
+// openDatabase opens the game database and imports new games of the saves
+// directory, so saved games are always searchable.
+func openDatabase(path string) (*gamedb.DB, error) {
+	db, err := gamedb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := os.Stat("saves"); err != nil {
+		return db, nil
+	}
+	added, err := db.ImportPath("saves")
+	if err != nil {
+		return nil, err
+	}
+	if added > 0 {
+		if err := db.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// gameInfos returns the games as listed by the game browser.
+func gameInfos(games []*gamedb.Game) []pgn.GameInfo {
+	infos := make([]pgn.GameInfo, len(games))
+	for i, g := range games {
+		infos[i] = g.Info()
+	}
+	return infos
+}
+
+// openDatabaseSearch opens the game browser on all games of the database.
+func openDatabaseSearch(g *gocui.Gui, v *gocui.View) error {
+	db, err := openDatabase(databasePath())
+	if err != nil {
+		showInfoMessage(g, fmt.Sprintf("Error opening the game database: %v", err))
+		return nil
+	}
+	search := func(query string) ([]pgn.GameInfo, error) {
+		q, err := gamedb.ParseQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		games, err := db.Search(q)
+		return gameInfos(games), err
+	}
+	hint := "Search games (e.g. carlsen eco:B9 date:2024 result:1-0) - Ctrl+p: shown position"
+	if err := openBrowser(g, gameInfos(db.Games()), filepath.Base(databasePath()), hint, search); err != nil {
+		return err
+	}
+	bindContext(g, gameDatabaseContext(), nil)
+	return nil
+}
+
+// gameDatabaseContext holds the keys the game browser has in addition when it
+// searches the game database.
+func gameDatabaseContext() keyContext {
+	return keyContext{name: "Game database", view: "browserFilter", bindings: []binding{
+		{keys: []string{"Ctrl+p"}, help: "search the shown position", handler: searchShownPosition},
+	}}
+}
+
+// searchShownPosition adds the position shown on the board to the search.
+func searchShownPosition(g *gocui.Gui, v *gocui.View) error {
+	query := strings.TrimSpace(v.Buffer())
+	term := fmt.Sprintf("fen:%q", history.GetPositionFEN(selectedPly()))
+	if query != "" {
+		term = query + " " + term
+	}
+	v.Clear()
+	fmt.Fprint(v, term)
+	v.SetCursor(len(term), 0)
+	updateBrowserMatches(v)
+	return nil
+}
+
+// runDBCommand imports games into the game database and searches it:
+//
+//	terminalchess db import [-db file] [file.pgn|dir ...]
+//	terminalchess db search [-db file] [-pgn] query
+func runDBCommand(args []string) error {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: terminalchess db import [-db file] [file.pgn|dir ...]")
+		fmt.Fprintln(os.Stderr, "       terminalchess db search [-db file] [-pgn] query")
+	}
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	// The database file defaults to the one of the terminal UI
+	if c, err := loadConfig("config.json"); err == nil {
+		cfg = c
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("config.json: %w", err)
+	}
+	fs := flag.NewFlagSet("db "+args[0], flag.ExitOnError)
+	dbPath := fs.String("db", databasePath(), "game database file")
+	printPGN := fs.Bool("pgn", false, "print the PGN of the found games instead of a list")
+	fs.Usage = func() {
+		usage()
+		fs.PrintDefaults()
+	}
+
+	switch args[0] {
+	case "import":
+		fs.Parse(args[1:])
+		db, err := gamedb.Open(*dbPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *dbPath, err)
+		}
+		paths := fs.Args()
+		if len(paths) == 0 {
+			paths = []string{"saves"}
+		}
+		for _, path := range paths {
+			added, err := db.ImportPath(path)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s: %d new games\n", path, added)
+		}
+		if err := db.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("%d games in %s\n", db.Len(), *dbPath)
+	case "search":
+		fs.Parse(args[1:])
+		db, err := gamedb.Open(*dbPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *dbPath, err)
+		}
+		q, err := gamedb.ParseQuery(strings.Join(fs.Args(), " "))
+		if err != nil {
+			return err
+		}
+		games, err := db.Search(q)
+		if err != nil {
+			return err
+		}
+		if *printPGN {
+			for _, g := range games {
+				fmt.Printf("%s\n\n", g.Raw)
+			}
+			return nil
+		}
+		fmt.Println(formatGameRow("#", "White", "Black", "Result", "Date", "Event", "ECO", "Moves"))
+		for _, g := range games {
+			fmt.Println(formatGameRow(fmt.Sprintf("%d", g.ID), g.Tag("White"), g.Tag("Black"), g.Tag("Result"),
+				g.Tag("Date"), g.Tag("Event"), g.Tag("ECO"), fmt.Sprintf("%d", (g.Plies+1)/2)))
+		}
+		fmt.Printf("%d of %d games\n", len(games), db.Len())
+	default:
+		usage()
+		os.Exit(2)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+var showHelp bool
+
+// helpWidth is the width of the help overlay including its borders.
+const helpWidth = 64
+
+// toggleHelp shows or hides the overlay listing the keys of every context.
+func toggleHelp(g *gocui.Gui, v *gocui.View) error {
+	if showHelp {
+		closeHelp(g)
+		return nil
+	}
+	showHelp = true
+	g.DeleteKeybindings("")
+	g.DeleteKeybindings("help")
+	bindContext(g, helpContext(), cfg.Keybindings)
+	return layout(g)
+}
+
+func closeHelp(g *gocui.Gui) {
+	showHelp = false
+	g.DeleteView("help")
+	g.SetCurrentView("board")
+	enableGlobalKeybindings(g, cfg.Keybindings)
+}
+
+func cancelHelp(g *gocui.Gui, v *gocui.View) error {
+	closeHelp(g)
+	return nil
+}
+
+func helpContext() keyContext {
+	return keyContext{name: "Help", view: "help", bindings: []binding{
+		{keys: []string{"Down", "j"}, help: "scroll down", handler: scrollHelp(1)},
+		{keys: []string{"Up", "k"}, help: "scroll up", handler: scrollHelp(-1)},
+		{keys: []string{"PgDn"}, help: "scroll down a page", handler: scrollHelpPage(1)},
+		{keys: []string{"PgUp"}, help: "scroll up a page", handler: scrollHelpPage(-1)},
+		{action: "toggleHelp", help: "close the help", handler: cancelHelp},
+		{keys: []string{"Esc", "q"}, help: "close the help", handler: cancelHelp},
+	}}
+}
+
+// layoutHelp draws the help overlay centered on top of everything else.
+func layoutHelp(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	x0 := max((maxX-helpWidth)/2, 0)
+	v, err := g.SetView("help", x0, 1, min(x0+helpWidth, maxX-1), maxY-2)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Keys - j/k: scroll, Esc: close"
+		v.Wrap = false
+		fmt.Fprint(v, helpText(cfg.Keybindings))
+		g.SetCurrentView("help")
+	}
+	return nil
+}
+
+// helpText lists the keys of every context with what they do. Keys of the
+// board come from keybindings, as configured in config.json.
+func helpText(keybindings map[string]string) string {
+	var sb strings.Builder
+	for i, c := range keyContexts() {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%s\n", c.name)
+		for _, b := range c.bindings {
+			fmt.Fprintf(&sb, "  %-14s %s\n", keyLabel(b.keySequences(keybindings)), b.help)
+		}
+	}
+	return sb.String()
+}
+
+// keyLabel shows key names the way the help lists them.
+func keyLabel(names []string) string {
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "":
+			labels = append(labels, "(unbound)")
+		case " ":
+			labels = append(labels, "Space")
+		default:
+			labels = append(labels, name)
+		}
+	}
+	return strings.Join(labels, ", ")
+}
+
+// scrollHelp returns a handler scrolling the help overlay by lines.
+func scrollHelp(lines int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		ox, oy := v.Origin()
+		_, height := v.Size()
+		last := len(v.BufferLines()) - height
+		oy = min(oy+lines, last)
+		return v.SetOrigin(ox, max(oy, 0))
+	}
+}
+
+// scrollHelpPage returns a handler scrolling the help overlay by pages.
+func scrollHelpPage(pages int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		_, height := v.Size()
+		return scrollHelp(pages*height)(g, v)
+	}
+}
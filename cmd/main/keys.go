@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jroimartin/gocui"
+)
+
+// binding is an action bound to keys. Actions named in the keybindings of
+// config.json take their keys from there and fall back to their default keys.
+type binding struct {
+	action  string   // Name in the keybindings of config.json
+	keys    []string // Fixed keys, or the default keys of an action, e.g. "Enter", "Ctrl+q" or "g g"
+	help    string
+	handler func(*gocui.Gui, *gocui.View) error
+}
+
+// keyContext is a set of bindings that are active together, such as those of
+// the board or of a dialog.
+type keyContext struct {
+	name     string // Title in the help overlay
+	view     string // View the keys are bound to, "" for all views
+	bindings []binding
+}
+
+// keyContexts returns all key contexts in the order the help overlay lists them.
+func keyContexts() []keyContext {
+	return []keyContext{
+		boardContext(),
+		commandLineContext(),
+		moveEntryContext(),
+		loadDialogContext(),
+		gameBrowserContext(),
+		gameDatabaseContext(),
+		tagEditorContext(),
+		resumePromptContext(),
+		helpContext(),
+	}
+}
+
+// boardContext holds the keys bound while no dialog is open, all of which may
+// be configured in config.json.
+func boardContext() keyContext {
+	return keyContext{name: "Board", bindings: []binding{
+		{action: "moveLeft", keys: []string{"h"}, help: "move left", handler: moveLeft},
+		{action: "moveDown", keys: []string{"j"}, help: "move down", handler: moveDown},
+		{action: "moveUp", keys: []string{"k"}, help: "move up", handler: moveUp},
+		{action: "moveRight", keys: []string{"l"}, help: "move right", handler: moveRight},
+		{action: "pick", keys: []string{"p"}, help: "pick a piece", handler: selectPiece},
+		{action: "drop", keys: []string{"d"}, help: "drop a piece", handler: dropPiece},
+		{action: "clearSelection", keys: []string{"c"}, help: "clear the selection", handler: clearSelection},
+		{action: "enterMove", keys: []string{"m"}, help: "type a move", handler: openMoveEntry},
+		{action: "engineMove", keys: []string{"e"}, help: "let the engine move", handler: engineMove},
+		{action: "difficultyUp", keys: []string{"+"}, help: "increase the engine difficulty", handler: changeDifficulty(1)},
+		{action: "difficultyDown", keys: []string{"-"}, help: "decrease the engine difficulty", handler: changeDifficulty(-1)},
+		{action: "historyBackward", keys: []string{"y"}, help: "move back in the move history", handler: historyPrev},
+		{action: "historyForward", keys: []string{"x"}, help: "move forward in the move history", handler: historyNext},
+		{action: "reset", keys: []string{"r"}, help: "start a new game", handler: reset},
+		{action: "newChess960", keys: []string{"n"}, help: "start a new Chess960 game", handler: newChess960Game},
+		{action: "saveGame", keys: []string{"w"}, help: "save the game into the saves directory", handler: saveGameAsPGN},
+		{action: "loadGame", keys: []string{"a"}, help: "load a game", handler: openLoadDialog},
+		{action: "searchDatabase", keys: []string{"S"}, help: "search the game database", handler: openDatabaseSearch},
+		{action: "copyPGN", keys: []string{"W"}, help: "copy the game PGN to the clipboard", handler: copyPGNToClipboard},
+		{action: "paste", keys: []string{"V"}, help: "paste a FEN or PGN from the clipboard", handler: pasteFromClipboard},
+		{action: "editTags", keys: []string{"I"}, help: "edit the PGN tags", handler: openTagEditor},
+		{action: "exportDiagram", keys: []string{"D"}, help: "export the shown position as PNG and SVG", handler: exportDiagram},
+		{action: "exportGIF", keys: []string{"G"}, help: "export the game as an animated GIF", handler: exportGIF},
+		{action: "toggleHistory", keys: []string{"t"}, help: "show/hide the move history", handler: toggleHistory},
+		{action: "toggleMetadata", keys: []string{"i"}, help: "show/hide the game info", handler: toggleMetadata},
+		{action: "switchBoard", keys: []string{"b"}, help: "flip the board", handler: switchBoard},
+		{action: "cycleTheme", keys: []string{"T"}, help: "switch to the next colour theme", handler: cycleTheme},
+		{action: "cyclePieces", keys: []string{"P"}, help: "switch to the next piece set", handler: cyclePieceSet},
+		{action: "commandLine", keys: []string{":"}, help: "open the command line", handler: openCommandLine},
+		{action: "toggleHelp", keys: []string{"?"}, help: "show/hide this help", handler: toggleHelp},
+		{action: "quit", keys: []string{"q"}, help: "quit", handler: quit},
+	}}
+}
+
+// keyMap maps actions to their keys as written in config.json: a key, keys
+// pressed one after the other separated by spaces, or a list of those
+// separated by commas. The list may also be given as a JSON array.
+type keyMap map[string]string
+
+func (m *keyMap) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = keyMap{}
+	for action, value := range raw {
+		var keys string
+		if err := json.Unmarshal(value, &keys); err == nil {
+			(*m)[action] = keys
+			continue
+		}
+		var list []string
+		if err := json.Unmarshal(value, &list); err != nil {
+			return fmt.Errorf("keybinding %s: expected a string or a list of strings", action)
+		}
+		for i, keys := range list {
+			// Alone these are keys, joined into a list they would be separators
+			switch keys {
+			case ",":
+				list[i] = "Comma"
+			case " ":
+				list[i] = "Space"
+			}
+		}
+		(*m)[action] = strings.Join(list, ", ")
+	}
+	return nil
+}
+
+// splitKeyList returns the key sequences of a configured key list.
+func splitKeyList(keys string) []string {
+	if utf8.RuneCountInString(keys) == 1 {
+		return []string{keys}
+	}
+	var list []string
+	for _, sequence := range strings.Split(keys, ",") {
+		list = append(list, strings.TrimSpace(sequence))
+	}
+	return list
+}
+
+// keySequences returns the key sequences bound to b.
+func (b binding) keySequences(keybindings map[string]string) []string {
+	if keys := keybindings[b.action]; b.action != "" && keys != "" {
+		return splitKeyList(keys)
+	}
+	return b.keys
+}
+
+// specialKeys maps the names of keys without a character to their gocui keys.
+var specialKeys = map[string]gocui.Key{
+	"enter":      gocui.KeyEnter,
+	"esc":        gocui.KeyEsc,
+	"tab":        gocui.KeyTab,
+	"space":      gocui.KeySpace,
+	"backspace":  gocui.KeyBackspace2,
+	"insert":     gocui.KeyInsert,
+	"delete":     gocui.KeyDelete,
+	"home":       gocui.KeyHome,
+	"end":        gocui.KeyEnd,
+	"up":         gocui.KeyArrowUp,
+	"down":       gocui.KeyArrowDown,
+	"left":       gocui.KeyArrowLeft,
+	"right":      gocui.KeyArrowRight,
+	"pgup":       gocui.KeyPgup,
+	"pgdn":       gocui.KeyPgdn,
+	"ctrl+space": gocui.KeyCtrlSpace,
+}
+
+// parseKey returns the gocui key of a key name: a single character, a special
+// key such as "Enter" or "F5", or a control key such as "Ctrl+q".
+func parseKey(name string) (interface{}, error) {
+	if name == " " {
+		// The terminal reports the space bar as a key, not a character
+		return gocui.KeySpace, nil
+	}
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		return r, nil
+	}
+	lower := strings.ToLower(name)
+	if key, ok := specialKeys[lower]; ok {
+		return key, nil
+	}
+	if lower == "comma" {
+		return ',', nil
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(lower, "f")); err == nil && lower[0] == 'f' && n >= 1 && n <= 12 {
+		// The function keys count down from F1
+		return gocui.KeyF1 - gocui.Key(n-1), nil
+	}
+	if letter, ok := strings.CutPrefix(lower, "ctrl+"); ok && len(letter) == 1 && letter[0] >= 'a' && letter[0] <= 'z' {
+		// Ctrl+a to Ctrl+z are the control characters 1 to 26
+		return gocui.KeyCtrlA + gocui.Key(letter[0]-'a'), nil
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no key given")
+	}
+	return nil, fmt.Errorf("unknown key %q", name)
+}
+
+// keyPress is a key pressed together with a modifier.
+type keyPress struct {
+	key interface{} // A rune or a gocui.Key
+	mod gocui.Modifier
+}
+
+// parseKeyPress reads a key name that may be prefixed with "Alt+".
+func parseKeyPress(name string) (keyPress, error) {
+	const alt = "alt+"
+	mod := gocui.ModNone
+	if len(name) > len(alt) && strings.ToLower(name[:len(alt)]) == alt {
+		name, mod = name[len(alt):], gocui.ModAlt
+	}
+	key, err := parseKey(name)
+	return keyPress{key: key, mod: mod}, err
+}
+
+// keyNames splits a key sequence into the names of its keys.
+func keyNames(sequence string) []string {
+	if names := strings.Fields(sequence); len(names) > 1 {
+		return names
+	}
+	return []string{strings.TrimSpace(sequence)}
+}
+
+// parseKeySequence reads keys pressed one after the other, separated by spaces.
+func parseKeySequence(sequence string) ([]keyPress, error) {
+	if utf8.RuneCountInString(sequence) == 1 {
+		press, err := parseKeyPress(sequence)
+		return []keyPress{press}, err
+	}
+	var presses []keyPress
+	for _, name := range keyNames(sequence) {
+		press, err := parseKeyPress(name)
+		if err != nil {
+			return nil, err
+		}
+		presses = append(presses, press)
+	}
+	return presses, nil
+}
+
+// keyNode is a node of the tree of the key sequences of a context. Bound nodes
+// run an action, the others wait for the next key of a sequence.
+type keyNode struct {
+	binding  *binding
+	sequence string // Keys leading to the node, as configured
+	children map[keyPress]*keyNode
+}
+
+// keyTree returns the tree of the key sequences of a context, and why some
+// keys cannot be bound: they are invalid, or bound to another action of the
+// context already.
+func keyTree(c keyContext, keybindings map[string]string) (*keyNode, []string) {
+	root := &keyNode{children: map[keyPress]*keyNode{}}
+	var problems []string
+	for i := range c.bindings {
+		b := &c.bindings[i]
+		for _, sequence := range b.keySequences(keybindings) {
+			presses, err := parseKeySequence(sequence)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s: %v", c.name, b.name(), err))
+				continue
+			}
+			if other := root.insert(presses, b, sequence); other != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q of %s conflicts with %q of %s",
+					c.name, sequence, b.name(), other.sequence, other.binding.name()))
+			}
+		}
+	}
+	return root, problems
+}
+
+// name returns the action of a binding, or what it does for fixed keys.
+func (b *binding) name() string {
+	if b.action != "" {
+		return b.action
+	}
+	return b.help
+}
+
+// insert adds the key sequence of a binding below the node. If the sequence,
+// a prefix of it or a longer sequence starting with it is bound already, it
+// returns the node bound to that instead.
+func (n *keyNode) insert(presses []keyPress, b *binding, sequence string) *keyNode {
+	names := keyNames(sequence)
+	node := n
+	for i, press := range presses {
+		child, ok := node.children[press]
+		if !ok {
+			child = &keyNode{sequence: strings.Join(names[:i+1], " "), children: map[keyPress]*keyNode{}}
+			node.children[press] = child
+		}
+		if child.binding == b {
+			return nil // The same keys listed twice
+		}
+		if child.binding != nil {
+			return child
+		}
+		node = child
+	}
+	if len(node.children) > 0 {
+		return node.firstBound()
+	}
+	node.binding = b
+	return nil
+}
+
+// firstBound returns the bound node below n with the smallest key sequence.
+func (n *keyNode) firstBound() *keyNode {
+	if n.binding != nil {
+		return n
+	}
+	var bound []*keyNode
+	for _, child := range n.children {
+		bound = append(bound, child.firstBound())
+	}
+	sort.Slice(bound, func(i, j int) bool { return bound[i].sequence < bound[j].sequence })
+	return bound[0]
+}
+
+// bindContext binds the keys of a context. Keys that cannot be bound are left
+// out, see keybindingProblems.
+func bindContext(g *gocui.Gui, c keyContext, keybindings map[string]string) {
+	root, _ := keyTree(c, keybindings)
+	bindKeyNode(g, c.view, root, root)
+}
+
+// pendingView is the view that cancels a pending key sequence on any key
+// without a binding, nil if no sequence is pending.
+var pendingView *gocui.View
+
+// bindKeyNode binds the keys continuing the sequences at node, which is root
+// until the first key of a longer sequence is pressed. A completed sequence
+// binds the keys of root again before it runs its action, so the action may
+// still replace them, e.g. with those of a dialog. Like in vim, any other key
+// cancels a pending sequence.
+func bindKeyNode(g *gocui.Gui, view string, node, root *keyNode) {
+	rebind := func(g *gocui.Gui, next *keyNode) {
+		g.DeleteKeybindings(view)
+		bindKeyNode(g, view, next, root)
+		if next == root && pendingView != nil {
+			pendingView.Editable, pendingView.Editor = false, nil
+			pendingView = nil
+		}
+	}
+	cancel := func(g *gocui.Gui) {
+		rebind(g, root)
+		showInfoMessage(g, "")
+	}
+	for press, child := range node.children {
+		child := child
+		var handler func(*gocui.Gui, *gocui.View) error
+		switch {
+		case child.binding == nil:
+			handler = func(g *gocui.Gui, v *gocui.View) error {
+				rebind(g, child)
+				if v == nil {
+					// No view has the focus until the first dialog is closed
+					v, _ = g.SetCurrentView("board")
+				}
+				if v != nil && !v.Editable {
+					// gocui hands keys without a binding to the editor of the current view
+					pendingView = v
+					v.Editable = true
+					v.Editor = gocui.EditorFunc(func(*gocui.View, gocui.Key, rune, gocui.Modifier) { cancel(g) })
+				}
+				showInfoMessage(g, child.sequence+" … (Esc: cancel)")
+				return nil
+			}
+		case node != root:
+			handler = func(g *gocui.Gui, v *gocui.View) error {
+				rebind(g, root)
+				showInfoMessage(g, "")
+				return child.binding.handler(g, v)
+			}
+		default:
+			handler = child.binding.handler
+		}
+		g.SetKeybinding(view, press.key, press.mod, handler)
+	}
+	if esc := (keyPress{key: gocui.KeyEsc}); node != root && node.children[esc] == nil {
+		g.SetKeybinding(view, esc.key, esc.mod, func(g *gocui.Gui, v *gocui.View) error {
+			cancel(g)
+			return nil
+		})
+	}
+}
+
+// keybindingProblems returns what is wrong with the configured keybindings:
+// unknown actions, invalid keys and keys bound to several actions.
+func keybindingProblems(keybindings map[string]string) []string {
+	actions := map[string]bool{}
+	for _, b := range boardContext().bindings {
+		actions[b.action] = true
+	}
+	var problems []string
+	for action := range keybindings {
+		if !actions[action] {
+			problems = append(problems, fmt.Sprintf("unknown action %q", action))
+		}
+	}
+	sort.Strings(problems)
+	for _, c := range keyContexts() {
+		_, contextProblems := keyTree(c, keybindings)
+		problems = append(problems, contextProblems...)
+	}
+	return problems
+}
+
+// reportKeybindingProblems logs the problems of the configured keybindings and
+// shows the first of them once the terminal UI runs.
+func reportKeybindingProblems(g *gocui.Gui, keybindings map[string]string) {
+	problems := keybindingProblems(keybindings)
+	if len(problems) == 0 {
+		return
+	}
+	for _, problem := range problems {
+		log.Printf("Invalid keybinding: %s", problem)
+	}
+	msg := "Keybindings: " + problems[0]
+	if len(problems) > 1 {
+		msg += fmt.Sprintf(" (and %d more, see logs/app.log)", len(problems)-1)
+	}
+	g.Update(func(g *gocui.Gui) error {
+		showInfoMessage(g, msg)
+		return nil
+	})
+}
@@ -2,30 +2,55 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/RubikNube/TerminalChess/pkg/clipboard"
 	"github.com/RubikNube/TerminalChess/pkg/engine"
 	"github.com/RubikNube/TerminalChess/pkg/gui"
 	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/RubikNube/TerminalChess/pkg/notation"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/RubikNube/TerminalChess/pkg/theme"
 	"github.com/RubikNube/TerminalChess/pkg/websocket"
 	"github.com/corentings/chess"
 	"github.com/jroimartin/gocui"
 )
 
 type Config struct {
-	Keybindings map[string]string `json:"keybindings"`
+	Keybindings keyMap `json:"keybindings"` // Keys by action, see keyMap
 	WebUI       struct {
 		Enable bool `json:"useWebUI"`
 		Port   int  `json:"port"`
 	} `json:"webUI"`
+	Clipboard struct {
+		Backends []string `json:"backends"` // Tried in order, see clipboard.DefaultBackends
+	} `json:"clipboard"`
+	Notation struct {
+		Style    string `json:"style"`    // san, figurine, long or uci
+		Language string `json:"language"` // Language of the piece letters, e.g. en, de, fr
+	} `json:"notation"`
+	Database struct {
+		Path string `json:"path"` // Game database file, saves/gamedb.json if empty
+	} `json:"database"`
+	Theme struct {
+		Name   string                 `json:"name"`   // Theme of the board, classic if empty
+		Colors string                 `json:"colors"` // Colour depth: auto, 16 or 256; truecolor is drawn with 256
+		Themes map[string]theme.Theme `json:"themes"` // Own themes by name, may replace built-in ones
+	} `json:"theme"`
+	Pieces struct {
+		Set string `json:"set"` // Directory below assets/pieces, default if empty
+	} `json:"pieces"`
+	Mouse struct {
+		Disable bool `json:"disable"` // Leave mouse events to the terminal, e.g. for selecting text
+	} `json:"mouse"`
 }
 
 var (
@@ -44,10 +69,13 @@ var (
 	infoMessage       string = "" // Message to show in the info view
 	cfg               Config
 	defaultLoadPrompt = "Enter path to PGN file:"
+	clip              *clipboard.Clipboard
 
-	cyclePrefix  string
 	cycleIndex   int
 	cycleMatches []string
+
+	chess960Flag      = flag.Bool("chess960", false, "start a Chess960 game")
+	startPositionFlag = flag.Int("sp", -1, "Chess960 start position number (0-959), random if not set")
 )
 
 func loadConfig(path string) (Config, error) {
@@ -61,8 +89,12 @@ func loadConfig(path string) (Config, error) {
 	return cfg, err
 }
 
+// minBoardWidth keeps the board view and the InfoView below it wide enough for
+// the title and messages when the board is drawn compact.
+const minBoardWidth = 40
+
 func layout(g *gocui.Gui) error {
-	_, maxY := g.Size()
+	maxX, maxY := g.Size()
 	historyWidth := 20
 
 	// Render load dialog if needed
@@ -91,42 +123,44 @@ func layout(g *gocui.Gui) error {
 		}
 	}
 
-	// Calculate the exact width needed for the chessboard view
-	artWidth := 7
-	squareWidth := artWidth*2 + 2
-	offset := 13
-	boardWidth := 2 + 8*squareWidth - offset // 2 for left label, 8 squares
+	// Draw the board as large as the terminal allows next to the side panes,
+	// re-evaluated on every resize
+	sideWidth := 0
+	if showHistory {
+		sideWidth += historyWidth
+	}
+	if showMetadata {
+		sideWidth += metadataWidth
+	}
+	gui.BoardRenderMode = gui.ChooseRenderMode(maxX-sideWidth-3, maxY-5)
+	contentWidth, _ := gui.BoardRenderMode.BoardSize()
+	boardWidth := max(contentWidth+3, minBoardWidth) // borders and one column of space
 
 	// Board view on the left
 	if v, err := g.SetView("board", 0, 0, boardWidth-1, maxY-4); err != nil {
 		if err != gocui.ErrUnknownView {
 			return err
 		}
-		v.Title = "Terminal Chess"
 		v.Wrap = false
 	}
 	if v, err := g.View("board"); err == nil {
+		v.Title = boardTitle()
 		// Show board at selected history index if navigating
 		if historyIndex >= 0 {
-			hist := history.GetHistory()
-			game := chess.NewGame()
-			for i := 0; i <= historyIndex && i < len(hist); i++ {
-				move, err := chess.UCINotation{}.Decode(game.Position(), hist[i])
-				if err == nil {
-					game.Move(move)
-				}
-			}
-			fen := game.Position().Board().String()
+			fen := history.GetPositionFEN(historyIndex)
 			tmpBoard := gui.NewChessBoardFromFEN(fen)
+			gui.BoardHighlights = boardHighlights(tmpBoard)
 			tmpBoard.RenderToView(v, cursor.Row, cursor.Col, selected, selectedRow, selectedCol)
 		} else {
+			gui.BoardHighlights = boardHighlights(board)
 			board.RenderToView(v, cursor.Row, cursor.Col, selected, selectedRow, selectedCol)
 		}
 	}
 
 	// History view on the right (only if showHistory is true)
 	if showHistory {
-		if v, err := g.SetView("history", boardWidth, 0, boardWidth+historyWidth-1, maxY-1); err != nil {
+		paneHeight := annotationPaneHeight(maxY)
+		if v, err := g.SetView("history", boardWidth, 0, boardWidth+historyWidth-1, maxY-paneHeight-1); err != nil {
 			if err != gocui.ErrUnknownView {
 				return err
 			}
@@ -137,18 +171,37 @@ func layout(g *gocui.Gui) error {
 			v.Clear()
 			historyLines := history.GetMoveHistorySAN()
 			for i, line := range historyLines {
-				if historyIndex >= 0 && i == historyIndex/2 {
+				if historyIndex >= 0 && i == history.HistoryLine(historyIndex) {
 					fmt.Fprintf(v, "> %s\n", line)
 				} else {
 					fmt.Fprintln(v, line)
 				}
 			}
 		}
+		if paneHeight > 0 {
+			if err := layoutAnnotations(g, boardWidth, maxY-paneHeight, boardWidth+historyWidth-1, maxY-1); err != nil {
+				return err
+			}
+		} else if _, err := g.View("annotation"); err == nil {
+			g.DeleteView("annotation")
+		}
 	} else {
 		// If the view exists but should not be shown, delete it
 		if _, err := g.View("history"); err == nil {
 			g.DeleteView("history")
 		}
+		if _, err := g.View("annotation"); err == nil {
+			g.DeleteView("annotation")
+		}
+	}
+
+	// Metadata view right of the history
+	metadataX := boardWidth
+	if showHistory {
+		metadataX += historyWidth
+	}
+	if err := layoutMetadata(g, metadataX); err != nil {
+		return err
 	}
 
 	// Info view below the board
@@ -159,6 +212,36 @@ func layout(g *gocui.Gui) error {
 		v.Wrap = false
 	}
 
+	if showCommandLine {
+		if err := layoutCommandLine(g, boardWidth-1); err != nil {
+			return err
+		}
+	}
+	if showMoveEntry {
+		if err := layoutMoveEntry(g, boardWidth-1); err != nil {
+			return err
+		}
+	}
+
+	// Game browser on top of everything else
+	if showGameBrowser {
+		if err := layoutGameBrowser(g); err != nil {
+			return err
+		}
+	}
+
+	if showResumePrompt {
+		if err := layoutResumePrompt(g); err != nil {
+			return err
+		}
+	}
+
+	if showHelp {
+		if err := layoutHelp(g); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -188,22 +271,31 @@ func selectPiece(g *gocui.Gui, v *gocui.View) error {
 
 func dropPiece(g *gocui.Gui, v *gocui.View) error {
 	if selected && selectedRow >= 0 && selectedCol >= 0 {
-		if board.MovePiece(selectedRow, selectedCol, cursor.Row, cursor.Col, turn) {
-			selected = false
-			if turn == gui.White {
-				turn = gui.Black
-			} else {
-				turn = gui.White
-			}
-			// If automove is enabled and it's now the engine's turn, trigger engine move
-			if engine.LoadedEngineConfig.Automove && ((engine.LoadedEngineConfig.EngineColor == "white" && turn == gui.White) || (engine.LoadedEngineConfig.EngineColor == "black" && turn == gui.Black)) {
-				engineMove(g, v)
-			}
-		}
+		playMove(g, v, selectedRow, selectedCol, cursor.Row, cursor.Col, gui.Queen)
 	}
 	return nil
 }
 
+// playMove plays the player's move and lets the engine reply if it is its turn.
+// It reports whether the move was legal.
+func playMove(g *gocui.Gui, v *gocui.View, fromRow, fromCol, toRow, toCol int, promotion gui.PieceType) bool {
+	if !board.MovePiecePromoting(fromRow, fromCol, toRow, toCol, turn, promotion) {
+		return false
+	}
+	selected = false
+	if turn == gui.White {
+		turn = gui.Black
+	} else {
+		turn = gui.White
+	}
+	autosave()
+	// If automove is enabled and it's now the engine's turn, trigger engine move
+	if engine.LoadedEngineConfig.Automove && ((engine.LoadedEngineConfig.EngineColor == "white" && turn == gui.White) || (engine.LoadedEngineConfig.EngineColor == "black" && turn == gui.Black)) {
+		engineMove(g, v)
+	}
+	return true
+}
+
 func quit(g *gocui.Gui, v *gocui.View) error {
 	return gocui.ErrQuit
 }
@@ -214,9 +306,44 @@ func reset(g *gocui.Gui, v *gocui.View) error {
 	cursor = gui.Cursor{Row: 0, Col: 0}
 	turn = gui.White
 	history.ClearHistory()
+	history.SetStartPosition("", false)
+	engine.SetOption("UCI_Chess960", false)
+	gameTags = newGameTags()
+	autosave()
 	return layout(g)
 }
 
+// startChess960 sets up a new Chess960 game from the start position sp and
+// switches the engine to Chess960 mode.
+func startChess960(sp int) error {
+	newBoard, err := gui.NewChess960Board(sp)
+	if err != nil {
+		return err
+	}
+	board = newBoard
+	turn = gui.White
+	historyIndex = -1
+	clearSelection(nil, nil)
+	history.ClearHistory()
+	history.SetStartPosition(board.ToFEN(gui.White), true)
+	engine.SetOption("UCI_Chess960", true)
+	gameTags = newGameTags()
+	autosave()
+	log.Printf("Started Chess960 game with start position %d", sp)
+	return nil
+}
+
+// newChess960Game starts a Chess960 game from a random start position.
+func newChess960Game(g *gocui.Gui, v *gocui.View) error {
+	sp := gui.RandomChess960Position()
+	if err := startChess960(sp); err != nil {
+		showInfoMessage(g, fmt.Sprintf("Failed to start Chess960 game: %v", err))
+		return nil
+	}
+	showInfoMessage(g, fmt.Sprintf("New Chess960 game, start position %d", sp))
+	return nil
+}
+
 func toggleHistory(g *gocui.Gui, v *gocui.View) error {
 	showHistory = !showHistory
 	return nil // layout will be called automatically on next refresh
@@ -224,6 +351,7 @@ func toggleHistory(g *gocui.Gui, v *gocui.View) error {
 
 func switchBoard(g *gocui.Gui, v *gocui.View) error {
 	gui.ToggleBoardOrientation()
+	autosave()
 	return nil
 }
 
@@ -274,7 +402,17 @@ func moveDown(g *gocui.Gui, v *gocui.View) error {
 
 func engineMove(g *gocui.Gui, v *gocui.View) error {
 	fen := board.ToFEN(turn)
-	bestMove, err := engine.GetBestMove(fen, 10)
+	// Prefer a book move while the position is still in the opening books,
+	// which only cover standard chess
+	var bestMove string
+	var inBook bool
+	if !gui.Chess960 {
+		bestMove, inBook = engine.BookMove(fen, len(history.GetHistory()))
+	}
+	var err error
+	if !inBook {
+		bestMove, err = engine.GetMove(fen)
+	}
 	if err != nil || bestMove == "" {
 		log.Println("Error: Could not get best move from Stockfish.")
 		return nil
@@ -287,17 +425,34 @@ func engineMove(g *gocui.Gui, v *gocui.View) error {
 	fromRow := 8 - int(bestMove[1]-'0')
 	toCol := int(bestMove[2] - 'a')
 	toRow := 8 - int(bestMove[3]-'0')
-	if board.MovePiece(fromRow, fromCol, toRow, toCol, turn) {
+	if board.MovePiecePromoting(fromRow, fromCol, toRow, toCol, turn, promotionPiece(bestMove)) {
 		// Switch turn after a successful move
 		if turn == gui.White {
 			turn = gui.Black
 		} else {
 			turn = gui.White
 		}
+		if record, ok := history.GetRecord(len(history.GetHistory()) - 1); ok {
+			showInfoMessage(g, "Engine played "+record.Format(history.GetNotation()))
+		}
+		autosave()
 	}
 	return nil
 }
 
+// changeDifficulty returns a handler that steps the engine difficulty up or down
+// and reports the new level in the InfoView.
+func changeDifficulty(step int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		oldName := engine.PlayerName()
+		level := engine.StepDifficulty(step)
+		renamePlayer(oldName, engine.PlayerName())
+		autosave()
+		showInfoMessage(g, "Difficulty: "+engine.Label(level))
+		return nil
+	}
+}
+
 func historyPrev(g *gocui.Gui, v *gocui.View) error {
 	hist := history.GetHistory()
 	if len(hist) == 0 {
@@ -334,94 +489,70 @@ func saveGameAsPGN(g *gocui.Gui, v *gocui.View) error {
 	}
 	timestamp := time.Now().Format("2006-01-02-15-04-05")
 	filename := fmt.Sprintf("chess_%s.pgn", timestamp)
-	filepath := filepath.Join(saveDir, filename)
-
-	hist := history.GetHistory()
-	game := chess.NewGame()
-	for _, moveStr := range hist {
-		move, err := chess.UCINotation{}.Decode(game.Position(), moveStr)
-		if err == nil {
-			game.Move(move)
-		}
-	}
-
-	playerName := os.Getenv("USER")
-	if playerName == "" {
-		playerName = "Player"
-	}
-	date := time.Now().Format("2006.01.02")
-
-	elo := 0
-	if eloOpt, ok := engine.LoadedEngineConfig.Options["UCI_Elo"]; ok {
-		switch v := eloOpt.(type) {
-		case float64:
-			elo = int(v)
-		case int:
-			elo = v
-		}
-	}
-
-	f, err := os.Create(filepath)
-	if err != nil {
+	if err := writePGN(filepath.Join(saveDir, filename)); err != nil {
 		showInfoMessage(g, fmt.Sprintf("Error creating PGN file: %v", err))
 		return nil
 	}
-	defer f.Close()
-
-	fmt.Fprintf(f, "[Event \"Casual Game\"]\n")
-	fmt.Fprintf(f, "[Date \"%s\"]\n", date)
-	// determine if the engine is playing white or black
-	if engine.LoadedEngineConfig.EngineColor == "white" {
-		fmt.Fprintf(f, "[White \"%s (Elo: %d)\"]\n", engine.LoadedEngineConfig.Name, elo)
-		fmt.Fprintf(f, "[Black \"%s\"]\n", playerName)
-	} else {
-		fmt.Fprintf(f, "[White \"%s\"]\n", playerName)
-		fmt.Fprintf(f, "[Black \"%s (Elo: %d)\"]\n", engine.LoadedEngineConfig.Name, elo)
-	}
-	fmt.Fprintf(f, "\n")
-
-	line := game.String()
-	if line != "" {
-		fmt.Fprintln(f, line)
-	}
 
 	notification := fmt.Sprintf("Game saved to saves/%s", filename)
 	showInfoMessage(g, notification)
 	return nil
 }
 
-// Copy the current game PGN to the clipboard and show notification in InfoView
-func copyPGNToClipboard(g *gocui.Gui, v *gocui.View) error {
-	hist := history.GetHistory()
-	game := chess.NewGame()
-	for _, moveStr := range hist {
-		move, err := chess.UCINotation{}.Decode(game.Position(), moveStr)
-		if err == nil {
-			game.Move(move)
+// writePGN writes the current game with its tags to a PGN file.
+func writePGN(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	pgn.WriteTags(f, exportTags())
+	fmt.Fprintf(f, "\n")
+	fmt.Fprintln(f, pgnMovetext())
+	return f.Close()
+}
+
+// pgnMovetext returns the PGN movetext of the recorded moves, followed by the result.
+// The moves are in English SAN as the PGN standard requires, not in the
+// configured notation.
+func pgnMovetext() string {
+	// Games set up from a FEN may start with Black to move or a later move number
+	moveNumber, blackToMove := history.MoveNumber(0)
+	var sb strings.Builder
+	commented := false // Black moves after a comment repeat the move number
+	for i, san := range history.GetMovesSAN() {
+		if (i == 0 || commented) && blackToMove {
+			fmt.Fprintf(&sb, "%d... ", moveNumber)
+		} else if !blackToMove {
+			fmt.Fprintf(&sb, "%d. ", moveNumber)
 		}
+		annotation := history.GetAnnotation(i)
+		sb.WriteString(san + annotation.Movetext() + " ")
+		commented = strings.Contains(annotation.Movetext(), "{")
+		if blackToMove {
+			moveNumber++
+		}
+		blackToMove = !blackToMove
 	}
-	pgn := game.String()
-	if pgn == "" {
+	sb.WriteString(gameResult())
+	return sb.String()
+}
+
+// Copy the current game PGN to the clipboard and show notification in InfoView
+func copyPGNToClipboard(g *gocui.Gui, v *gocui.View) error {
+	if len(history.GetHistory()) == 0 {
 		showInfoMessage(g, "No moves to copy.")
 		return nil
 	}
+	pgn := pgnMovetext()
 
-	// Use xclip to copy to clipboard (Linux)
-	cmd := exec.Command("wl-copy")
-	stdin, err := cmd.StdinPipe()
+	backend, err := clip.Copy(pgn)
 	if err != nil {
+		log.Printf("Failed to copy PGN to clipboard: %v", err)
 		showInfoMessage(g, "Failed to access clipboard.")
 		return nil
 	}
-	if err := cmd.Start(); err != nil {
-		showInfoMessage(g, "Failed to start clipboard command.")
-		return nil
-	}
-	_, _ = io.WriteString(stdin, pgn)
-	stdin.Close()
-	cmd.Wait()
 
-	log.Println("Copied PGN to clipboard: '" + pgn + "'")
+	log.Println("Copied PGN to clipboard with " + backend + ": '" + pgn + "'")
 	showInfoMessage(g, "PGN copied to clipboard.")
 	return nil
 }
@@ -444,72 +575,100 @@ func handleLoadGame(g *gocui.Gui, v *gocui.View) error {
 		}
 	}
 	log.Println("Trying to open path:", path)
-	f, err := os.Open(path)
+	games, err := readPGNFile(path)
 	if err != nil {
-		showInfoMessage(g, fmt.Sprintf("Failed to open file: %v", err))
+		showInfoMessage(g, fmt.Sprintf("Failed to load game: %v", err))
+		return nil
+	}
+	// Files with several games open in the game browser
+	if len(games) > 1 {
+		closeLoadDialog(g)
+		return openGameBrowser(g, games, path)
+	}
+	if !loadPGNGame(g, games[0].Raw, path) {
 		return nil
 	}
+	closeLoadDialog(g)
+	return layout(g)
+}
+
+// readPGNFile reads the games of a PGN file.
+func readPGNFile(path string) ([]pgn.GameInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
 	defer f.Close()
 	data, err := io.ReadAll(f)
 	if err != nil {
-		showInfoMessage(g, fmt.Sprintf("Failed to read file: %v", err))
-		return nil
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
 	}
-	gameFunc, err := chess.PGN(strings.NewReader(string(data)))
+	games, err := pgn.ReadGames(strings.NewReader(string(data)))
 	if err != nil {
-		showInfoMessage(g, "Invalid PGN file.")
-		return nil
+		return nil, fmt.Errorf("invalid PGN file %s: %w", path, err)
+	}
+	if len(games) == 0 {
+		return nil, fmt.Errorf("no games in %s", path)
 	}
-	game := chess.NewGame()
-	gameFunc(game)
-	parsedGame := game
-	if parsedGame == nil {
+	return games, nil
+}
+
+func closeLoadDialog(g *gocui.Gui) {
+	showLoadDialog = false
+	g.DeleteView("load")
+	g.SetCurrentView("board")
+	enableGlobalKeybindings(g, cfg.Keybindings)
+}
+
+// loadPGNGame replaces the current game with the given PGN game. It reports
+// errors in the InfoView and returns false if the game could not be loaded.
+func loadPGNGame(g *gocui.Gui, raw string, source string) bool {
+	gameFunc, err := chess.PGN(strings.NewReader(raw))
+	if err != nil {
 		showInfoMessage(g, "Invalid PGN file.")
-		return nil
+		return false
 	}
+	parsedGame := chess.NewGame(gameFunc)
 	history.ClearHistory()
+	gui.UseStandardCastling()
+	engine.SetOption("UCI_Chess960", false)
+	startFEN := parsedGame.Positions()[0].String()
+	if startFEN == chess.StartingPosition().String() {
+		startFEN = ""
+	}
+	history.SetStartPosition(startFEN, false)
+	gameTags = pgn.ParseTags(raw)
 	for _, move := range parsedGame.Moves() {
 		history.AddMove(chess.UCINotation{}.Encode(parsedGame.Position(), move))
 	}
+	// Keep comments, NAGs and commands of the main line moves
+	if _, annotated := pgn.ParseMainline(pgn.Movetext(raw)); len(annotated) == len(parsedGame.Moves()) {
+		for i, move := range annotated {
+			history.SetAnnotation(i, move.Annotation)
+		}
+	}
 	board = gui.NewChessBoardFromFEN(parsedGame.FEN())
-	showLoadDialog = false
-	g.DeleteView("load")
-	g.SetCurrentView("board")
-	enableGlobalKeybindings(g, cfg.Keybindings)
-	showInfoMessage(g, fmt.Sprintf("Loaded game from %s", path))
-	return layout(g)
+	gui.SetEnPassantSquareFromFEN(parsedGame.FEN())
+	historyIndex = -1
+	turn = sideToMove(parsedGame.FEN())
+	autosave()
+	showInfoMessage(g, fmt.Sprintf("Loaded game from %s", source))
+	return true
 }
 
 // Autocomplete file path in load dialog when Tab is pressed
 func autocompleteFilePath(g *gocui.Gui, v *gocui.View) error {
-	buf := v.Buffer()
-	input := strings.TrimSpace(buf)
-	dir, _ := filepath.Split(input)
-	if dir == "" {
-		dir = "."
-	}
-
-	// If no cached matches, update suggestions first
-	if len(cycleMatches) == 0 {
-		updateFilePathSuggestions(g, v)
-	}
+	return completeFilePathInView(g, v, 1)
+}
 
-	// Only use cached matches when Tab is pressed; do not recalculate here
-	if len(cycleMatches) == 1 {
-		// Single match, autocomplete directly
-		input = filepath.Join(dir, cycleMatches[0])
-		cycleMatches = nil
-		cycleIndex = 0
-	} else if len(cycleMatches) > 1 {
-		// Cycle through cached matches only, do not recalculate
-		input = filepath.Join(dir, cycleMatches[cycleIndex])
-		showInfoMessage(g, "Matches: "+strings.Join(cycleMatches, " "))
-		cycleIndex = (cycleIndex + 1) % len(cycleMatches)
-	} else {
-		cycleMatches = nil
-		cycleIndex = 0
-	}
+// Cycle backwards through suggestions when Shift+Tab is pressed
+func autocompleteFilePathBackward(g *gocui.Gui, v *gocui.View) error {
+	return completeFilePathInView(g, v, -1)
+}
 
+// completeFilePathInView replaces the path in v with its next completion.
+func completeFilePathInView(g *gocui.Gui, v *gocui.View, step int) error {
+	input := completeFilePath(g, strings.TrimSpace(v.Buffer()), step)
 	v.Clear()
 	fmt.Fprint(v, input)
 	// Move cursor to end of input
@@ -517,41 +676,47 @@ func autocompleteFilePath(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
-// Cycle backwards through suggestions when Shift+Tab is pressed
-func autocompleteFilePathBackward(g *gocui.Gui, v *gocui.View) error {
-	buf := v.Buffer()
-	input := strings.TrimSpace(buf)
+// completeFilePath returns the next completion of a file path, cycling through
+// the matching directory entries in the given direction (1 or -1).
+func completeFilePath(g *gocui.Gui, input string, step int) string {
 	dir, _ := filepath.Split(input)
 	if dir == "" {
 		dir = "."
 	}
+	if match, ok := nextCompletion(g, func() []string { return filePathMatches(input) }, step); ok {
+		return filepath.Join(dir, match)
+	}
+	return input
+}
 
+// nextCompletion returns the next of the cached completion candidates, which
+// are computed with find if there are none. A single candidate is returned
+// directly, several are cycled through in the given direction.
+func nextCompletion(g *gocui.Gui, find func() []string, step int) (string, bool) {
 	if len(cycleMatches) == 0 {
-		updateFilePathSuggestions(g, v)
+		cycleMatches = find()
+		cycleIndex = 0
 	}
-
-	if len(cycleMatches) == 1 {
-		input = filepath.Join(dir, cycleMatches[0])
+	switch {
+	case len(cycleMatches) == 1:
+		match := cycleMatches[0]
 		cycleMatches = nil
 		cycleIndex = 0
-	} else if len(cycleMatches) > 1 {
-		input = filepath.Join(dir, cycleMatches[cycleIndex])
+		return match, true
+	case len(cycleMatches) > 1:
+		// Cycle through cached matches only, do not recalculate
+		match := cycleMatches[cycleIndex]
 		showInfoMessage(g, "Matches: "+strings.Join(cycleMatches, " "))
-		cycleIndex = (cycleIndex - 1 + len(cycleMatches)) % len(cycleMatches)
-	} else {
-		cycleMatches = nil
-		cycleIndex = 0
+		cycleIndex = (cycleIndex + step + len(cycleMatches)) % len(cycleMatches)
+		return match, true
 	}
-
-	v.Clear()
-	fmt.Fprint(v, input)
-	v.SetCursor(len(input), 0)
-	return nil
+	cycleMatches = nil
+	cycleIndex = 0
+	return "", false
 }
 
-func updateFilePathSuggestions(g *gocui.Gui, v *gocui.View) error {
-	buf := v.Buffer()
-	input := strings.TrimSpace(buf)
+// filePathMatches returns the names of the directory entries completing input.
+func filePathMatches(input string) []string {
 	dir, filePrefix := filepath.Split(input)
 	if dir == "" {
 		dir = "."
@@ -573,14 +738,10 @@ func updateFilePathSuggestions(g *gocui.Gui, v *gocui.View) error {
 	// Check if dir is a valid directory before reading
 	info, err := os.Stat(dir)
 	if err != nil || !info.IsDir() {
-		cycleMatches = nil
-		cycleIndex = 0
 		return nil
 	}
 	entries, err := os.ReadDir(dir)
 	if err != nil {
-		cycleMatches = nil
-		cycleIndex = 0
 		return nil
 	}
 	var matches []string
@@ -590,73 +751,38 @@ func updateFilePathSuggestions(g *gocui.Gui, v *gocui.View) error {
 			matches = append(matches, name)
 		}
 	}
-	cyclePrefix = filePrefix
-	cycleMatches = matches
-	cycleIndex = 0
-	return nil
+	return matches
 }
 
 func enableGlobalKeybindings(g *gocui.Gui, keybindings map[string]string) {
 	g.DeleteKeybindings("")
 	g.DeleteKeybindings("load")
-	moveLeftKey := []rune(keybindings["moveLeft"])[0]
-	moveRightKey := []rune(keybindings["moveRight"])[0]
-	moveUpKey := []rune(keybindings["moveUp"])[0]
-	moveDownKey := []rune(keybindings["moveDown"])[0]
-	selectKey := []rune(keybindings["pick"])[0]
-	clearSelectionKey := []rune(keybindings["clearSelection"])[0]
-	quitKey := []rune(keybindings["quit"])[0]
-	resetKey := []rune(keybindings["reset"])[0]
-	dropKey := []rune(keybindings["drop"])[0]
-	toggleHistoryKey := []rune(keybindings["toggleHistory"])[0]
-	switchBoardKey := []rune(keybindings["switchBoard"])[0]
-	engineMoveKey := []rune(keybindings["engineMove"])[0]
-	forwardHistoryKey := []rune(keybindings["historyForward"])[0]
-	backwardHistoryKey := []rune(keybindings["historyBackward"])[0]
-	saveGameKey := []rune(keybindings["saveGame"])[0]
-	loadGameKey := []rune(keybindings["loadGame"])[0]
-	copyPGNKey := []rune(keybindings["copyPGN"])[0]
-
-	g.SetKeybinding("", moveLeftKey, gocui.ModNone, moveLeft)
-	g.SetKeybinding("", moveRightKey, gocui.ModNone, moveRight)
-	g.SetKeybinding("", moveUpKey, gocui.ModNone, moveUp)
-	g.SetKeybinding("", moveDownKey, gocui.ModNone, moveDown)
-	g.SetKeybinding("", selectKey, gocui.ModNone, selectPiece)
-	g.SetKeybinding("", dropKey, gocui.ModNone, dropPiece)
-	g.SetKeybinding("", quitKey, gocui.ModNone, quit)
-	g.SetKeybinding("", resetKey, gocui.ModNone, reset)
-	g.SetKeybinding("", toggleHistoryKey, gocui.ModNone, toggleHistory)
-	g.SetKeybinding("", switchBoardKey, gocui.ModNone, switchBoard)
-	g.SetKeybinding("", engineMoveKey, gocui.ModNone, engineMove)
-	g.SetKeybinding("", backwardHistoryKey, gocui.ModNone, historyPrev)
-	g.SetKeybinding("", forwardHistoryKey, gocui.ModNone, historyNext)
-	g.SetKeybinding("", saveGameKey, gocui.ModNone, saveGameAsPGN)
-	g.SetKeybinding("", clearSelectionKey, gocui.ModNone, clearSelection)
-	g.SetKeybinding("", loadGameKey, gocui.ModNone, openLoadDialog)
-	g.SetKeybinding("", copyPGNKey, gocui.ModNone, copyPGNToClipboard)
+	g.DeleteKeybindings("browserFilter")
+	g.DeleteKeybindings("tags")
+	g.DeleteKeybindings("command")
+	g.DeleteKeybindings("move")
+	g.DeleteKeybindings("help")
+	bindContext(g, boardContext(), keybindings)
+}
+
+func cancelLoadDialog(g *gocui.Gui, v *gocui.View) error {
+	closeLoadDialog(g)
+	return layout(g)
+}
+
+func loadDialogContext() keyContext {
+	return keyContext{name: "Load dialog", view: "load", bindings: []binding{
+		{keys: []string{"Enter"}, help: "load the file", handler: handleLoadGame},
+		{keys: []string{"Tab", "Ctrl+x"}, help: "complete the file name", handler: autocompleteFilePath},
+		{keys: []string{"Ctrl+y"}, help: "previous completion", handler: autocompleteFilePathBackward},
+		{keys: []string{"Esc", "Ctrl+q"}, help: "cancel", handler: cancelLoadDialog},
+	}}
 }
 
 func enableLoadDialogKeybindings(g *gocui.Gui) {
 	g.DeleteKeybindings("")
 	g.DeleteKeybindings("load")
-	g.SetKeybinding("load", gocui.KeyEnter, gocui.ModNone, handleLoadGame)
-	g.SetKeybinding("load", gocui.KeyEsc, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
-		showLoadDialog = false
-		enableGlobalKeybindings(g, cfg.Keybindings)
-		g.DeleteView("load")
-		g.SetCurrentView("board")
-		return layout(g)
-	})
-	g.SetKeybinding("load", gocui.KeyCtrlQ, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
-		showLoadDialog = false
-		enableGlobalKeybindings(g, cfg.Keybindings)
-		g.DeleteView("load")
-		g.SetCurrentView("board")
-		return layout(g)
-	})
-	g.SetKeybinding("load", gocui.KeyTab, gocui.ModNone, autocompleteFilePath)
-	g.SetKeybinding("load", gocui.KeyCtrlX, gocui.ModNone, autocompleteFilePath)
-	g.SetKeybinding("load", gocui.KeyCtrlY, gocui.ModNone, autocompleteFilePathBackward)
+	bindContext(g, loadDialogContext(), nil)
 	// Remove default prompt when user types any character
 	g.SetKeybinding("load", 0, gocui.ModNone, clearLoadPromptOnRune)
 }
@@ -731,16 +857,26 @@ func startTerminalUI() {
 	log.Println("Starting Terminal UI...")
 	keybindings := cfg.Keybindings
 
-	g, err := gocui.NewGui(gocui.OutputNormal)
+	g, err := gocui.NewGui(outputMode())
 	if err != nil {
 		log.Panicln(err)
 	}
 	defer g.Close()
+	if cfg.Theme.Name != "" {
+		if err := applyTheme(cfg.Theme.Name); err != nil {
+			log.Printf("Invalid theme configuration, using %s: %v", theme.Default, err)
+		}
+	}
 
-	defaultPiecesPath := filepath.Join("assets", "pieces", "default")
-	err = gui.LoadAsciiPieces(defaultPiecesPath)
-	if err != nil {
-		log.Panicln("Failed to load ASCII pieces:", err)
+	if cfg.Pieces.Set != "" && cfg.Pieces.Set != defaultPieceSet {
+		if err := loadPieceSet(cfg.Pieces.Set); err != nil {
+			log.Printf("Invalid piece set configuration, using %s: %v", defaultPieceSet, err)
+		}
+	}
+	if pieceSet == defaultPieceSet {
+		if err := loadPieceSet(defaultPieceSet); err != nil {
+			log.Panicln("Failed to load ASCII pieces:", err)
+		}
 	}
 	board = gui.NewChessBoard()
 	cursor = gui.Cursor{Row: 6, Col: 4}
@@ -749,7 +885,39 @@ func startTerminalUI() {
 
 	engine.Initialize("engine.json")
 
+	clip, err = clipboard.New(cfg.Clipboard.Backends, os.Stdout)
+	if err != nil {
+		log.Printf("Invalid clipboard configuration, using the default backends: %v", err)
+		clip, _ = clipboard.New(nil, os.Stdout)
+	}
+	moveNotation, err := notation.New(cfg.Notation.Style, cfg.Notation.Language)
+	if err != nil {
+		log.Printf("Invalid notation configuration, using SAN: %v", err)
+	}
+	history.SetNotation(moveNotation)
+	gameTags = newGameTags()
+
+	if *chess960Flag {
+		sp := *startPositionFlag
+		if sp < 0 {
+			sp = gui.RandomChess960Position()
+		}
+		if err := startChess960(sp); err != nil {
+			log.Panicln("Failed to start Chess960 game:", err)
+		}
+	}
+
+	reportKeybindingProblems(g, keybindings)
 	enableGlobalKeybindings(g, keybindings)
+	if !cfg.Mouse.Disable {
+		g.Mouse = true
+		enableMouseBindings(g)
+	}
+	if !*chess960Flag {
+		offerResume(g)
+	} else {
+		autosaveEnabled = true
+	}
 
 	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
 		log.Panicln(err)
@@ -757,6 +925,8 @@ func startTerminalUI() {
 }
 
 func main() {
+	flag.Parse()
+
 	// Ensure "logs" directory exists and set up logging to "logs/app.log"
 	logDir := "logs"
 	if err := os.MkdirAll(logDir, 0755); err != nil {
@@ -772,6 +942,30 @@ func main() {
 	defer logFile.Close()
 	log.SetOutput(logFile)
 
+	// Subcommands run without the terminal UI
+	if flag.NArg() > 0 {
+		var err error
+		switch flag.Arg(0) {
+		case "epd":
+			err = runEPDCommand(flag.Args()[1:])
+		case "diagram":
+			err = runDiagramCommand(flag.Args()[1:])
+		case "gif":
+			err = runGIFCommand(flag.Args()[1:])
+		case "db":
+			err = runDBCommand(flag.Args()[1:])
+		case "stats":
+			err = runStatsCommand(flag.Args()[1:])
+		default:
+			err = fmt.Errorf("unknown command %q", flag.Arg(0))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log.Println("Starting Terminal Chess...")
 	// Load config
 	cfg, err = loadConfig("config.json")
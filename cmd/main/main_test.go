@@ -1,11 +1,18 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/RubikNube/TerminalChess/pkg/engine"
 	"github.com/RubikNube/TerminalChess/pkg/gui"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/jroimartin/gocui"
 )
 
 func TestLoadConfig_Success(t *testing.T) {
@@ -65,3 +72,485 @@ func TestMovePiece_InvalidMove(t *testing.T) {
 		t.Error("Expected move to fail from empty square")
 	}
 }
+
+func TestSavedGame_Finished(t *testing.T) {
+	history.ClearHistory()
+	defer history.ClearHistory()
+	gameTags = newGameTags()
+	history.AddMove("f2f3")
+	if s := currentGame(); s.finished() {
+		t.Error("Expected a game in progress to be offered for resuming")
+	}
+	// Fool's mate
+	for _, move := range []string{"e7e5", "g2g4", "d8h4"} {
+		history.AddMove(move)
+	}
+	if s := currentGame(); !s.finished() || pgn.TagValue(s.Tags, "Result") != "0-1" {
+		t.Errorf("Expected the checkmate to be saved as the result, got %v", s.Tags)
+	}
+	if pgn.TagValue(gameTags, "Result") != "*" {
+		t.Error("Expected the tags of the game to be left alone")
+	}
+}
+
+func TestSavedGame_RoundTrip(t *testing.T) {
+	defer func() {
+		history.ClearHistory()
+		history.SetStartPosition("", false)
+		gui.UseStandardCastling()
+	}()
+	if err := startChess960(0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	history.AddMove("e2e4")
+	history.SetAnnotation(0, pgn.Annotation{Comment: "Opening", Clock: "0:04:58"})
+	gameTags = []pgn.Tag{{Name: "Event", Value: "Test"}, {Name: "Annotator", Value: "Coach"}}
+
+	data, err := json.Marshal(currentGame())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "autosave.json")
+	if err := writeSavedGame(path, data); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Start over and resume
+	history.ClearHistory()
+	gui.UseStandardCastling()
+	gameTags = nil
+	saved, err := loadSavedGame(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := restoreGame(saved); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !gui.Chess960 || turn != gui.Black {
+		t.Errorf("Expected a Chess960 game with Black to move, got Chess960=%v turn=%v", gui.Chess960, turn)
+	}
+	if h := history.GetHistory(); len(h) != 1 || h[0] != "e2e4" {
+		t.Errorf("Expected the move to be restored, got %v", h)
+	}
+	if a := history.GetAnnotation(0); a.Clock != "0:04:58" || a.Comment != "Opening" {
+		t.Errorf("Expected the annotation to be restored, got %+v", a)
+	}
+	if pgn.TagValue(gameTags, "Annotator") != "Coach" {
+		t.Errorf("Expected custom tags to be restored, got %v", gameTags)
+	}
+}
+
+func TestGotoCommand(t *testing.T) {
+	history.ClearHistory()
+	defer history.ClearHistory()
+	defer func() { historyIndex = -1 }()
+	for _, move := range []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5"} {
+		history.AddMove(move)
+	}
+	tests := []struct {
+		arg  string
+		want int
+	}{
+		{"1", 0},
+		{"2...", 3},
+		{"3", -1}, // The last move is the latest position
+		{"end", -1},
+	}
+	for _, tt := range tests {
+		historyIndex = 1
+		if err := executeCommand(nil, "goto "+tt.arg); err != nil {
+			t.Fatal(err)
+		}
+		if historyIndex != tt.want {
+			t.Errorf(":goto %s shows ply %d, want %d", tt.arg, historyIndex, tt.want)
+		}
+	}
+	// Unique prefixes select the command
+	historyIndex = -1
+	executeCommand(nil, "go 1...")
+	if historyIndex != 1 {
+		t.Errorf("Expected :go to run :goto, got ply %d", historyIndex)
+	}
+}
+
+func TestGotoCommand_FromFEN(t *testing.T) {
+	history.ClearHistory()
+	history.SetStartPosition("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 7", false)
+	defer history.SetStartPosition("", false)
+	defer history.ClearHistory()
+	defer func() { historyIndex = -1 }()
+	for _, move := range []string{"e7e5", "g1f3", "b8c6", "f1b5"} {
+		history.AddMove(move)
+	}
+	if got := history.GetMoveHistorySAN(); len(got) != 3 || got[0] != "7... e5" || got[1] != "8. Nf3 Nc6" || got[2] != "9. Bb5" {
+		t.Errorf("Unexpected history lines %q", got)
+	}
+	for _, tt := range []struct {
+		arg  string
+		want int
+	}{
+		{"7...", 0},
+		{"8", 1},
+		{"8...", 2},
+	} {
+		historyIndex = -1
+		if err := executeCommand(nil, "goto "+tt.arg); err != nil {
+			t.Fatal(err)
+		}
+		if historyIndex != tt.want {
+			t.Errorf(":goto %s shows ply %d, want %d", tt.arg, historyIndex, tt.want)
+		}
+		if line := history.HistoryLine(historyIndex); line != (tt.want+1)/2 {
+			t.Errorf("Ply %d is marked on line %d", historyIndex, line)
+		}
+	}
+}
+
+func TestCommandCompletionCandidates(t *testing.T) {
+	if got := withPrefix(commandNames(), "s"); len(got) != 2 || got[0] != "save" || got[1] != "search" {
+		t.Errorf("Unexpected commands for prefix s: %v", got)
+	}
+	if got := withPrefix(completeEngineCommand(0), "d"); len(got) != 2 || got[0] != "depth" || got[1] != "difficulty" {
+		t.Errorf("Unexpected engine settings for prefix d: %v", got)
+	}
+}
+
+func TestPlayTypedMove(t *testing.T) {
+	history.ClearHistory()
+	defer history.ClearHistory()
+	board = gui.NewChessBoard()
+	turn = gui.White
+	defer func() { board, turn = gui.NewChessBoard(), gui.White }()
+	for _, move := range []string{"Nf3", "d7d5", "d4", "Nf6"} {
+		if err := playTypedMoveText(nil, move); err != nil {
+			t.Fatalf("Playing %s: %v", move, err)
+		}
+	}
+	if board[5][5].Type != gui.Knight || board[4][3].Type != gui.Pawn || board[2][5].Type != gui.Knight || turn != gui.White {
+		t.Error("Expected Nf3, d5, d4 and Nf6 to be played")
+	}
+	if err := playTypedMoveText(nil, "Nd2"); err == nil {
+		t.Error("Expected Nd2 to be ambiguous")
+	}
+	if err := playTypedMoveText(nil, "Ke3"); err == nil {
+		t.Error("Expected Ke3 to be illegal")
+	}
+	if len(history.GetHistory()) != 4 {
+		t.Errorf("Expected 4 moves in the history, got %d", len(history.GetHistory()))
+	}
+}
+
+func TestLoadFEN_EnPassant(t *testing.T) {
+	defer history.ClearHistory()
+	defer history.SetStartPosition("", false)
+	defer func() { board, turn = gui.NewChessBoard(), gui.White }()
+	loadFEN("rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3")
+	if err := playTypedMoveText(nil, "exf6"); err != nil {
+		t.Fatalf("Expected the en passant capture to be legal: %v", err)
+	}
+	if board[3][5].Type != gui.Empty || board[2][5].Type != gui.Pawn {
+		t.Error("Expected the f5 pawn to be captured en passant")
+	}
+
+	// An en passant square of the previous game must not leak into the next
+	loadFEN("rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2")
+	if err := playTypedMoveText(nil, "f5"); err != nil {
+		t.Fatal(err)
+	}
+	loadFEN("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if fen := board.ToFEN(turn); strings.Fields(fen)[3] != "-" {
+		t.Errorf("Expected no en passant square after loading a FEN, got %s", fen)
+	}
+}
+
+func TestLoadFEN_AfterChess960(t *testing.T) {
+	defer history.ClearHistory()
+	defer history.SetStartPosition("", false)
+	defer func() { board, turn = gui.NewChessBoard(), gui.White }()
+	if err := startChess960(518); err != nil {
+		t.Fatal(err)
+	}
+	if engine.LoadedEngineConfig.Options["UCI_Chess960"] != true {
+		t.Fatal("Expected the engine to play Chess960")
+	}
+	loadFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if engine.LoadedEngineConfig.Options["UCI_Chess960"] != false {
+		t.Error("Expected the engine to leave Chess960 mode when a FEN is loaded")
+	}
+	// Castling is the standard king move again
+	if uci, err := parseTypedMove("O-O"); err != nil || uci != "e1g1" {
+		t.Errorf("O-O = %q, %v; want e1g1", uci, err)
+	}
+}
+
+func TestParseTypedMove_Chess960Castling(t *testing.T) {
+	b, err := gui.NewChess960Board(959) // RKRNNQBB
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gui.UseStandardCastling()
+	board, turn = b, gui.White
+	defer func() { board = gui.NewChessBoard() }()
+	if uci, err := parseTypedMove("O-O"); err != nil || uci != "b1c1" {
+		t.Errorf("O-O = %q, %v; want b1c1", uci, err)
+	}
+	if uci, err := parseTypedMove("0-0-0"); err != nil || uci != "b1a1" {
+		t.Errorf("0-0-0 = %q, %v; want b1a1", uci, err)
+	}
+}
+
+func TestHistoryPlyAt(t *testing.T) {
+	tests := []struct {
+		line string
+		x    int
+		want int
+	}{
+		{"1. e4 e5", 3, 0},
+		{"1. e4 e5", 6, 1},
+		{"2. Nf3", 8, 2},
+		{"> 10. Bxf7+ Kxf7", 7, 18},
+		{"> 10. Bxf7+ Kxf7", 13, 19},
+		{"", 0, -1},
+	}
+	for _, tt := range tests {
+		if got := historyPlyAt(tt.line, tt.x); got != tt.want {
+			t.Errorf("historyPlyAt(%q, %d) = %d, want %d", tt.line, tt.x, got, tt.want)
+		}
+	}
+	// Games set up from a FEN number their moves from the start position
+	history.SetStartPosition("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 7", false)
+	defer history.SetStartPosition("", false)
+	for _, tt := range []struct {
+		line string
+		x    int
+		want int
+	}{
+		{"7... e5", 5, 0},
+		{"8. Nf3 Nc6", 3, 1},
+		{"> 8. Nf3 Nc6", 10, 2},
+	} {
+		if got := historyPlyAt(tt.line, tt.x); got != tt.want {
+			t.Errorf("historyPlyAt(%q, %d) = %d, want %d", tt.line, tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestBoardHighlights(t *testing.T) {
+	history.ClearHistory()
+	defer history.ClearHistory()
+	defer func() { historyIndex = -1 }()
+	if h := boardHighlights(gui.NewChessBoard()); len(h.LastMove) != 0 || len(h.Check) != 0 {
+		t.Errorf("Expected no highlights before the first move, got %+v", h)
+	}
+	for _, move := range []string{"e2e4", "e7e5", "d1h5", "b8c6", "f1c4", "g8f6", "h5f7"} {
+		history.AddMove(move)
+	}
+	historyIndex = -1
+	h := boardHighlights(gui.NewChessBoardFromFEN(history.GetPositionFEN(6)))
+	want := []gui.Square{{Row: 3, Col: 7}, {Row: 1, Col: 5}}
+	if len(h.LastMove) != 2 || h.LastMove[0] != want[0] || h.LastMove[1] != want[1] {
+		t.Errorf("Expected the last move h5-f7, got %+v", h.LastMove)
+	}
+	if len(h.Check) != 1 || h.Check[0] != (gui.Square{Row: 0, Col: 4}) {
+		t.Errorf("Expected the black king on e8 in check, got %+v", h.Check)
+	}
+	historyIndex = 1
+	if h := boardHighlights(gui.NewChessBoardFromFEN(history.GetPositionFEN(1))); len(h.Check) != 0 || h.LastMove[1] != (gui.Square{Row: 3, Col: 4}) {
+		t.Errorf("Expected e7-e5 without check, got %+v", h)
+	}
+}
+
+func TestHelpText_ListsConfiguredKeys(t *testing.T) {
+	cfg, err := loadConfig("../../config.json")
+	if err != nil {
+		t.Fatalf("Failed to load config.json: %v", err)
+	}
+	text := helpText(cfg.Keybindings)
+	for _, c := range keyContexts() {
+		if !strings.Contains(text, c.name+"\n") {
+			t.Errorf("Expected the help to list the context %s", c.name)
+		}
+		for _, b := range c.bindings {
+			for _, name := range b.keySequences(cfg.Keybindings) {
+				if _, err := parseKey(name); err != nil {
+					t.Errorf("%s: %s: %v", c.name, b.help, err)
+				}
+			}
+		}
+	}
+	for _, b := range boardContext().bindings {
+		line := fmt.Sprintf("  %-14s %s\n", keyLabel([]string{cfg.Keybindings[b.action]}), b.help)
+		if !strings.Contains(text, line) {
+			t.Errorf("Expected the help to contain %q", line)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want interface{}
+	}{
+		{"h", 'h'},
+		{"?", '?'},
+		{" ", gocui.KeySpace},
+		{"Enter", gocui.KeyEnter},
+		{"esc", gocui.KeyEsc},
+		{"PgDn", gocui.KeyPgdn},
+		{"Ctrl+q", gocui.KeyCtrlQ},
+		{"Ctrl+A", gocui.KeyCtrlA},
+		{"F1", gocui.KeyF1},
+		{"f5", gocui.KeyF5},
+		{"F12", gocui.KeyF12},
+		{"Left", gocui.KeyArrowLeft},
+		{"Backspace", gocui.KeyBackspace2},
+		{"Comma", ','},
+	}
+	for _, tt := range tests {
+		got, err := parseKey(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("parseKey(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+	for _, name := range []string{"", "Hyper+x", "Ctrl+1", "F13", "F0"} {
+		if _, err := parseKey(name); err == nil {
+			t.Errorf("parseKey(%q): expected an error", name)
+		}
+	}
+}
+
+func TestLoadConfig_KeyLists(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "config.json")
+	cfgData := `{"keybindings":{"moveLeft":["h","Left"],"moveRight":"l, Right","difficultyUp":[",","Alt+u"],"quit":"Ctrl+q"}}`
+	if err := os.WriteFile(tmpFile, []byte(cfgData), 0644); err != nil {
+		t.Fatalf("Failed to write temp config: %v", err)
+	}
+	cfg, err := loadConfig(tmpFile)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := map[string]string{"moveLeft": "h, Left", "moveRight": "l, Right", "difficultyUp": "Comma, Alt+u", "quit": "Ctrl+q"}
+	for action, keys := range want {
+		if cfg.Keybindings[action] != keys {
+			t.Errorf("Expected %s to be %q, got %q", action, keys, cfg.Keybindings[action])
+		}
+	}
+
+	if err := os.WriteFile(tmpFile, []byte(`{"keybindings":{"quit":42}}`), 0644); err != nil {
+		t.Fatalf("Failed to write temp config: %v", err)
+	}
+	if _, err := loadConfig(tmpFile); err == nil {
+		t.Error("Expected an error for a key that is no string")
+	}
+}
+
+func TestParseKeySequence(t *testing.T) {
+	presses, err := parseKeySequence("g Alt+x F5")
+	want := []keyPress{{key: 'g'}, {key: 'x', mod: gocui.ModAlt}, {key: gocui.KeyF5}}
+	if err != nil || len(presses) != len(want) {
+		t.Fatalf("parseKeySequence = %v, %v, want %v", presses, err, want)
+	}
+	for i := range want {
+		if presses[i] != want[i] {
+			t.Errorf("Key %d: got %v, want %v", i, presses[i], want[i])
+		}
+	}
+	if presses, err := parseKeySequence(" "); err != nil || len(presses) != 1 || presses[0].key != gocui.KeySpace {
+		t.Errorf("Expected a single space to be the space bar, got %v, %v", presses, err)
+	}
+	if _, err := parseKeySequence("g Nope"); err == nil {
+		t.Error("Expected an error for an unknown key in a sequence")
+	}
+}
+
+func TestKeybindingProblems(t *testing.T) {
+	if problems := keybindingProblems(cfg.Keybindings); len(problems) != 0 {
+		t.Errorf("Expected no problems without configured keys, got %v", problems)
+	}
+	config, err := loadConfig("../../config.json")
+	if err != nil {
+		t.Fatalf("Failed to load config.json: %v", err)
+	}
+	if problems := keybindingProblems(config.Keybindings); len(problems) != 0 {
+		t.Errorf("Expected no problems in config.json, got %v", problems)
+	}
+
+	problems := keybindingProblems(map[string]string{
+		"moveLeft":    "h, Left",
+		"moveRight":   "h",   // Same key as moveLeft
+		"reset":       "g g", // Sequence
+		"newChess960": "g",   // Prefix of the reset sequence
+		"quit":        "Ctrl+9",
+		"teleport":    "z",
+	})
+	for _, want := range []string{
+		`unknown action "teleport"`,
+		`Board: "h" of moveRight conflicts with "h" of moveLeft`,
+		`Board: "g" of newChess960 conflicts with "g g" of reset`,
+		`Board: quit: unknown key "Ctrl+9"`,
+	} {
+		found := false
+		for _, problem := range problems {
+			found = found || problem == want
+		}
+		if !found {
+			t.Errorf("Expected problem %q, got %v", want, problems)
+		}
+	}
+	if len(problems) != 4 {
+		t.Errorf("Expected 4 problems, got %v", problems)
+	}
+}
+
+func TestKeyTree_Defaults(t *testing.T) {
+	root, problems := keyTree(boardContext(), map[string]string{"moveLeft": "Left, g h"})
+	if len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v", problems)
+	}
+	if n := root.children[keyPress{key: gocui.KeyArrowLeft}]; n == nil || n.binding.action != "moveLeft" {
+		t.Errorf("Expected Left to move left, got %+v", n)
+	}
+	if n := root.children[keyPress{key: 'g'}]; n == nil || n.binding != nil || n.children[keyPress{key: 'h'}].binding.action != "moveLeft" {
+		t.Errorf("Expected g h to move left, got %+v", n)
+	}
+	if n := root.children[keyPress{key: 'h'}]; n != nil {
+		t.Errorf("Expected h to be unbound once moveLeft is configured, got %+v", n)
+	}
+	if n := root.children[keyPress{key: 'q'}]; n == nil || n.binding.action != "quit" {
+		t.Errorf("Expected the unconfigured quit action on its default key q, got %+v", n)
+	}
+}
+
+func TestAnnotationPaneHeight(t *testing.T) {
+	tests := []struct{ maxY, want int }{
+		{50, annotationHeight},
+		{24, 8},
+		{13, 4},
+		{9, 3},
+		{8, 0}, // Too small for a line, the history takes all rows
+		{4, 0},
+	}
+	for _, tt := range tests {
+		if got := annotationPaneHeight(tt.maxY); got != tt.want {
+			t.Errorf("annotationPaneHeight(%d) = %d, want %d", tt.maxY, got, tt.want)
+		}
+	}
+}
+
+func TestReadPGNFile_Errors(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pgn")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{empty, filepath.Join(t.TempDir(), "missing.pgn")} {
+		_, err := readPGNFile(path)
+		if err == nil {
+			t.Errorf("Expected an error for %s", path)
+			continue
+		}
+		// Errors are lower case; the InfoView adds its own prefix
+		if msg := err.Error(); msg[0] < 'a' || msg[0] > 'z' {
+			t.Errorf("Expected a lower case error, got %q", msg)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/gui"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/jroimartin/gocui"
+)
+
+var (
+	dragging         bool // A piece was picked with the mouse button still pressed
+	dragRow, dragCol int  // Square the dragged piece was picked from
+)
+
+// boardHasFocus reports whether no dialog is open, so mouse clicks may change
+// the game.
+func boardHasFocus(g *gocui.Gui) bool {
+	v := g.CurrentView()
+	return v == nil || v.Name() == "board"
+}
+
+// clickedSquare returns the square under the mouse in the board view.
+func clickedSquare(v *gocui.View) (row, col int, ok bool) {
+	cx, cy := v.Cursor()
+	ox, oy := v.Origin()
+	return gui.SquareAt(cx+ox, cy+oy, gui.BoardFlipped)
+}
+
+// boardClick moves the cursor to the clicked square. It plays the selected
+// piece there, or picks the piece standing there.
+func boardClick(g *gocui.Gui, v *gocui.View) error {
+	row, col, ok := clickedSquare(v)
+	if !ok || !boardHasFocus(g) {
+		return nil
+	}
+	cursor = gui.Cursor{Row: row, Col: col}
+	dragging = false
+	if selected && (row != selectedRow || col != selectedCol) &&
+		playMove(g, v, selectedRow, selectedCol, row, col, gui.Queen) {
+		return nil
+	}
+	if piece := board[row][col]; piece.Type != gui.Empty && piece.Color == turn {
+		selectPiece(g, v)
+		dragging = true
+		dragRow, dragCol = row, col
+	}
+	return nil
+}
+
+// boardRelease drops a piece dragged with the mouse onto the square the button
+// is released on. A release on the square it was picked from keeps the piece
+// selected, so it can be played with a second click.
+func boardRelease(g *gocui.Gui, v *gocui.View) error {
+	if !dragging {
+		return nil
+	}
+	dragging = false
+	row, col, ok := clickedSquare(v)
+	if !ok || !boardHasFocus(g) || (row == dragRow && col == dragCol) {
+		return nil
+	}
+	cursor = gui.Cursor{Row: row, Col: col}
+	playMove(g, v, dragRow, dragCol, row, col, gui.Queen)
+	return nil
+}
+
+// historyClick shows the position after the clicked move of the history view.
+func historyClick(g *gocui.Gui, v *gocui.View) error {
+	if !boardHasFocus(g) {
+		return nil
+	}
+	cx, cy := v.Cursor()
+	line, err := v.Line(cy)
+	if err != nil {
+		return nil
+	}
+	ply := historyPlyAt(line, cx)
+	moves := len(history.GetHistory())
+	if ply < 0 || ply >= moves {
+		return nil
+	}
+	historyIndex = ply
+	if ply == moves-1 {
+		historyIndex = -1
+	}
+	return nil
+}
+
+// historyPlyAt returns the ply of the move at column x of a line of the move
+// history, e.g. 3 for Black's move in "2. Nf3 Nc6". It is -1 if the line
+// holds no move.
+func historyPlyAt(line string, x int) int {
+	if strings.HasPrefix(line, "> ") {
+		line, x = line[2:], x-2
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return -1
+	}
+	n, err := strconv.Atoi(strings.TrimRight(fields[0], "."))
+	if err != nil {
+		return -1
+	}
+	if strings.HasSuffix(fields[0], "...") {
+		// A game set up with Black to move starts with Black's move alone
+		return history.MovePly(n, true)
+	}
+	ply := history.MovePly(n, false)
+	if len(fields) > 2 && x >= len([]rune(strings.Join(fields[:2], " "))) {
+		ply++ // Black's move
+	}
+	return ply
+}
+
+// scrollHistory returns a handler scrolling the history view by lines.
+func scrollHistory(lines int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		ox, oy := v.Origin()
+		_, height := v.Size()
+		last := len(history.GetMoveHistorySAN()) - height
+		oy += lines
+		if oy > last {
+			oy = last
+		}
+		if oy < 0 {
+			oy = 0
+		}
+		return v.SetOrigin(ox, oy)
+	}
+}
+
+func enableMouseBindings(g *gocui.Gui) {
+	g.SetKeybinding("board", gocui.MouseLeft, gocui.ModNone, boardClick)
+	g.SetKeybinding("board", gocui.MouseRelease, gocui.ModNone, boardRelease)
+	g.SetKeybinding("history", gocui.MouseLeft, gocui.ModNone, historyClick)
+	g.SetKeybinding("history", gocui.MouseWheelUp, gocui.ModNone, scrollHistory(-1))
+	g.SetKeybinding("history", gocui.MouseWheelDown, gocui.ModNone, scrollHistory(1))
+}
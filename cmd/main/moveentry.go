@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/gui"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/jroimartin/gocui"
+)
+
+var showMoveEntry bool
+
+// moveEntryTitle is the title of the move entry box before anything is typed.
+const moveEntryTitle = "Move - type SAN or UCI, Enter: play, Esc: cancel"
+
+// maxMoveCompletions is the number of possible moves listed while typing.
+const maxMoveCompletions = 8
+
+func openMoveEntry(g *gocui.Gui, v *gocui.View) error {
+	showMoveEntry = true
+	enableMoveEntryKeybindings(g)
+	return layout(g)
+}
+
+func closeMoveEntry(g *gocui.Gui) {
+	showMoveEntry = false
+	g.DeleteView("move")
+	g.Cursor = false
+	g.SetCurrentView("board")
+	enableGlobalKeybindings(g, cfg.Keybindings)
+}
+
+// layoutMoveEntry draws the move entry box over the InfoView, which ends at x1.
+func layoutMoveEntry(g *gocui.Gui, x1 int) error {
+	_, maxY := g.Size()
+	v, err := g.SetView("move", 0, maxY-3, x1, maxY-1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = moveEntryTitle
+		v.Editable = true
+		v.Editor = gocui.EditorFunc(moveEntryEditor)
+		g.SetCurrentView("move")
+		g.Cursor = true
+	}
+	return nil
+}
+
+// moveEntryEditor edits the typed move and shows in the title what it matches.
+func moveEntryEditor(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	if key == gocui.KeyEnter {
+		return
+	}
+	gocui.DefaultEditor.Edit(v, key, ch, mod)
+	v.Title = moveFeedback(strings.TrimSpace(v.Buffer()))
+}
+
+// moveFeedback describes what the move typed so far matches: the move Enter
+// plays, the moves it may become, or why it is no legal move.
+func moveFeedback(input string) string {
+	if input == "" {
+		return moveEntryTitle
+	}
+	n := history.GetNotation()
+	fen := typedMoveFEN()
+	uci, err := parseTypedMove(input)
+	if err == nil {
+		return "Enter: play " + n.FormatUCI(fen, uci)
+	}
+	if completions := n.Complete(fen, input); len(completions) > 0 {
+		if len(completions) > maxMoveCompletions {
+			completions = append(completions[:maxMoveCompletions], "…")
+		}
+		return "Possible: " + strings.Join(completions, " ")
+	}
+	return err.Error()
+}
+
+// typedMoveFEN returns the position typed moves are read in. Chess960 castling
+// is left out, as the chess library only knows standard castling.
+func typedMoveFEN() string {
+	fen := board.ToFEN(turn)
+	if gui.Chess960 {
+		fen = history.WithoutCastling(fen)
+	}
+	return fen
+}
+
+// parseTypedMove reads a move typed in the shown position and returns it in
+// UCI. Chess960 castling is returned as the king moving onto its rook.
+func parseTypedMove(input string) (string, error) {
+	if gui.Chess960 {
+		castle := strings.ToUpper(strings.NewReplacer("0", "O", "-", "", "+", "", "#", "").Replace(input))
+		if castle == "OO" || castle == "OOO" {
+			row, fromCol, toCol := gui.Chess960CastlingMove(turn, castle == "OO")
+			return squareName(row, fromCol) + squareName(row, toCol), nil
+		}
+	}
+	return history.GetNotation().Parse(typedMoveFEN(), input)
+}
+
+// squareName returns the name of the square at board row and column, e.g. e4.
+func squareName(row, col int) string {
+	return fmt.Sprintf("%c%d", 'a'+col, 8-row)
+}
+
+// promotionPiece returns the piece a move given in UCI promotes to, a queen if
+// it names none.
+func promotionPiece(uci string) gui.PieceType {
+	if len(uci) < 5 {
+		return gui.Queen
+	}
+	switch uci[4] {
+	case 'r':
+		return gui.Rook
+	case 'b':
+		return gui.Bishop
+	case 'n':
+		return gui.Knight
+	}
+	return gui.Queen
+}
+
+// playTypedMove plays the move typed in the move entry box. The box stays open
+// with the error in its title if the move cannot be played.
+func playTypedMove(g *gocui.Gui, v *gocui.View) error {
+	input := strings.TrimSpace(v.Buffer())
+	if input == "" {
+		closeMoveEntry(g)
+		return nil
+	}
+	if err := playTypedMoveText(g, input); err != nil {
+		v.Title = err.Error()
+		return nil
+	}
+	closeMoveEntry(g)
+	return nil
+}
+
+// playTypedMoveText plays a move given in SAN or UCI like a dropped piece.
+func playTypedMoveText(g *gocui.Gui, input string) error {
+	uci, err := parseTypedMove(input)
+	if err != nil {
+		return err
+	}
+	fromCol, fromRow := int(uci[0]-'a'), 8-int(uci[1]-'0')
+	toCol, toRow := int(uci[2]-'a'), 8-int(uci[3]-'0')
+	if !playMove(g, nil, fromRow, fromCol, toRow, toCol, promotionPiece(uci)) {
+		return fmt.Errorf("illegal move %q", input)
+	}
+	return nil
+}
+
+func cancelMoveEntry(g *gocui.Gui, v *gocui.View) error {
+	closeMoveEntry(g)
+	return nil
+}
+
+func moveEntryContext() keyContext {
+	return keyContext{name: "Move entry", view: "move", bindings: []binding{
+		{keys: []string{"Enter"}, help: "play the move", handler: playTypedMove},
+		{keys: []string{"Esc", "Ctrl+q"}, help: "cancel", handler: cancelMoveEntry},
+	}}
+}
+
+func enableMoveEntryKeybindings(g *gocui.Gui) {
+	g.DeleteKeybindings("")
+	g.DeleteKeybindings("move")
+	bindContext(g, moveEntryContext(), nil)
+}
@@ -0,0 +1,12 @@
+package main
+
+import "github.com/RubikNube/TerminalChess/pkg/history"
+
+// boardTitle returns the title of the board view with the opening of the
+// shown position.
+func boardTitle() string {
+	if o, ok := history.Opening(selectedPly()); ok {
+		return "Terminal Chess - " + o.String()
+	}
+	return "Terminal Chess"
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/engine"
+	"github.com/RubikNube/TerminalChess/pkg/gui"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/corentings/chess"
+	"github.com/jroimartin/gocui"
+)
+
+// sideToMove returns the side to move of a FEN.
+func sideToMove(fen string) gui.Color {
+	if fields := strings.Fields(fen); len(fields) > 1 && fields[1] == "b" {
+		return gui.Black
+	}
+	return gui.White
+}
+
+// pasteFromClipboard imports a FEN or PGN from the clipboard.
+func pasteFromClipboard(g *gocui.Gui, v *gocui.View) error {
+	text, err := clip.Paste()
+	if err != nil {
+		log.Printf("Failed to paste from clipboard: %v", err)
+		showInfoMessage(g, "Failed to read clipboard.")
+		return nil
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		showInfoMessage(g, "Clipboard is empty.")
+		return nil
+	}
+	if _, err := chess.FEN(text); err == nil && !strings.Contains(text, "\n") {
+		loadFEN(text)
+		showInfoMessage(g, "Position pasted from clipboard.")
+		return nil
+	}
+	games, err := pgn.ReadGames(strings.NewReader(text))
+	if err != nil || len(games) == 0 {
+		showInfoMessage(g, "Clipboard contains no FEN or PGN.")
+		return nil
+	}
+	if len(games) > 1 {
+		return openGameBrowser(g, games, "clipboard")
+	}
+	loadPGNGame(g, games[0].Raw, "clipboard")
+	return nil
+}
+
+// loadFEN starts a new game from the position of fen.
+func loadFEN(fen string) {
+	gui.UseStandardCastling()
+	engine.SetOption("UCI_Chess960", false)
+	board = gui.NewChessBoardFromFEN(fen)
+	gui.SetEnPassantSquareFromFEN(fen)
+	turn = sideToMove(fen)
+	historyIndex = -1
+	clearSelection(nil, nil)
+	history.ClearHistory()
+	history.SetStartPosition(fen, false)
+	gameTags = newGameTags()
+	autosave()
+}
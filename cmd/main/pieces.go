@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/gui"
+	"github.com/jroimartin/gocui"
+)
+
+// piecesDir holds the piece sets, one per directory.
+var piecesDir = filepath.Join("assets", "pieces")
+
+// defaultPieceSet is the piece set used if none or an invalid one is configured.
+const defaultPieceSet = "default"
+
+var pieceSet = defaultPieceSet
+
+// loadPieceSet draws the pieces with the named set from piecesDir.
+func loadPieceSet(name string) error {
+	if err := gui.LoadAsciiPieces(filepath.Join(piecesDir, name)); err != nil {
+		return err
+	}
+	pieceSet = name
+	return nil
+}
+
+// cyclePieceSet switches to the next piece set in alphabetical order.
+func cyclePieceSet(g *gocui.Gui, v *gocui.View) error {
+	sets, err := gui.PieceSets(piecesDir)
+	if err != nil || len(sets) == 0 {
+		showInfoMessage(g, fmt.Sprintf("No piece sets found in %s", piecesDir))
+		return nil
+	}
+	next := sets[0]
+	for i, name := range sets {
+		if name == pieceSet {
+			next = sets[(i+1)%len(sets)]
+		}
+	}
+	if err := loadPieceSet(next); err != nil {
+		showInfoMessage(g, fmt.Sprintf("Invalid piece set: %v", err))
+		return nil
+	}
+	showInfoMessage(g, "Pieces: "+next)
+	return nil
+}
+
+func completePiecesCommand(arg int) []string {
+	if arg == 0 {
+		sets, _ := gui.PieceSets(piecesDir)
+		return sets
+	}
+	return nil
+}
+
+// runPiecesCommand switches to the named piece set, or lists the sets.
+func runPiecesCommand(g *gocui.Gui, args []string) error {
+	if len(args) == 0 {
+		sets, _ := gui.PieceSets(piecesDir)
+		showInfoMessage(g, fmt.Sprintf("Pieces: %s (available: %s)", pieceSet, strings.Join(sets, ", ")))
+		return nil
+	}
+	if err := loadPieceSet(args[0]); err != nil {
+		showInfoMessage(g, fmt.Sprintf("Invalid piece set: %v", err))
+		return nil
+	}
+	showInfoMessage(g, "Pieces: "+args[0])
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RubikNube/TerminalChess/pkg/gamedb"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/RubikNube/TerminalChess/pkg/stats"
+)
+
+// runStatsCommand reports the results of saved games against the engine:
+//
+//	terminalchess stats [-format text|csv] [-openings n] [-db file] [file.pgn|dir ...]
+func runStatsCommand(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	format := fs.String("format", "text", "report format: text or csv")
+	maxOpenings := fs.Int("openings", 10, "number of openings to list in the text report, 0 for all")
+	dbPath := fs.String("db", "", "read the games from this game database instead of PGN files")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: terminalchess stats [options] [file.pgn|dir ...]")
+		fmt.Fprintln(fs.Output(), "Reads the saves directory if no files are given.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *format != "text" && *format != "csv" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var infos []pgn.GameInfo
+	if *dbPath != "" {
+		db, err := gamedb.Open(*dbPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *dbPath, err)
+		}
+		infos = gameInfos(db.Games())
+	} else {
+		paths := fs.Args()
+		if len(paths) == 0 {
+			paths = []string{"saves"}
+		}
+		for _, path := range paths {
+			games, err := readPGNPath(path)
+			if err != nil {
+				return err
+			}
+			infos = append(infos, games...)
+		}
+	}
+
+	games := make([]stats.Game, len(infos))
+	for i, info := range infos {
+		games[i] = stats.FromPGN(info)
+	}
+	report := stats.Build(games)
+	if *format == "csv" {
+		return stats.WriteCSV(os.Stdout, report)
+	}
+	return stats.WriteText(os.Stdout, report, *maxOpenings)
+}
+
+// readPGNPath reads the games of a PGN file, or of all .pgn files below a directory.
+func readPGNPath(path string) ([]pgn.GameInfo, error) {
+	var games []pgn.GameInfo
+	err := pgn.WalkFiles(path, func(p string, r io.Reader) error {
+		fileGames, err := pgn.ReadGames(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		games = append(games, fileGames...)
+		return nil
+	})
+	return games, err
+}
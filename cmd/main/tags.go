@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/RubikNube/TerminalChess/pkg/engine"
+	"github.com/RubikNube/TerminalChess/pkg/gui"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/jroimartin/gocui"
+)
+
+var (
+	gameTags      []pgn.Tag // Tag pairs of the current game, in PGN order
+	showMetadata  bool      // Track if the metadata view is shown
+	showTagEditor bool
+)
+
+// newGameTags returns the seven tag roster for a new game against the engine.
+func newGameTags() []pgn.Tag {
+	playerName := os.Getenv("USER")
+	if playerName == "" {
+		playerName = "Player"
+	}
+	white, black := playerName, engine.PlayerName()
+	// determine if the engine is playing white or black
+	if engine.LoadedEngineConfig.EngineColor == "white" {
+		white, black = black, white
+	}
+	return []pgn.Tag{
+		{Name: "Event", Value: "Casual Game"},
+		{Name: "Site", Value: "?"},
+		{Name: "Date", Value: time.Now().Format("2006.01.02")},
+		{Name: "Round", Value: "?"},
+		{Name: "White", Value: white},
+		{Name: "Black", Value: black},
+		{Name: "Result", Value: "*"},
+	}
+}
+
+// gameResult returns the result of the current game. A finished position
+// decides the result, otherwise the Result tag is kept.
+func gameResult() string {
+	if result := history.GetResult(); result != "*" {
+		return result
+	}
+	switch result := pgn.TagValue(gameTags, "Result"); result {
+	case "1-0", "0-1", "1/2-1/2":
+		return result
+	}
+	return "*"
+}
+
+// exportTags returns the tags to save with the game. The Result and set up
+// tags are brought in line with the moves played, and ECO and Opening tags are
+// added for a known opening.
+func exportTags() []pgn.Tag {
+	tags := append([]pgn.Tag(nil), gameTags...)
+	tags = pgn.SetTag(tags, "Result", gameResult())
+	if gui.Chess960 {
+		tags = pgn.SetTag(tags, "Variant", "Chess960")
+	}
+	if startFEN := history.GetStartPosition(); startFEN != "" {
+		tags = pgn.SetTag(tags, "SetUp", "1")
+		tags = pgn.SetTag(tags, "FEN", startFEN)
+	} else {
+		tags = pgn.RemoveTag(tags, "SetUp")
+		tags = pgn.RemoveTag(tags, "FEN")
+	}
+	// Player tags without an Elo only tell the engine apart with its type
+	for _, side := range []string{"White", "Black"} {
+		if pgn.TagValue(tags, side) == engine.PlayerName() {
+			tags = pgn.SetTag(tags, side+"Type", "program")
+		}
+	}
+	// Tags of loaded games take precedence over the classification
+	if o, ok := history.Opening(len(history.GetHistory()) - 1); ok && pgn.TagValue(tags, "ECO") == "" {
+		tags = pgn.SetTag(tags, "ECO", o.ECO)
+		tags = pgn.SetTag(tags, "Opening", o.Name)
+	}
+	return tags
+}
+
+// metadataWidth is the width of the game info pane.
+const metadataWidth = 36
+
+// layoutMetadata shows the tag pairs of the game in a column right of x.
+func layoutMetadata(g *gocui.Gui, x int) error {
+	_, maxY := g.Size()
+	if !showMetadata {
+		if _, err := g.View("metadata"); err == nil {
+			g.DeleteView("metadata")
+		}
+		return nil
+	}
+	v, err := g.SetView("metadata", x, 0, x+metadataWidth-1, maxY-1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Game Info"
+		v.Wrap = false
+	}
+	v.Clear()
+	for _, t := range exportTags() {
+		fmt.Fprintf(v, "%-8s %s\n", t.Name, t.Value)
+	}
+	return nil
+}
+
+func toggleMetadata(g *gocui.Gui, v *gocui.View) error {
+	showMetadata = !showMetadata
+	return nil
+}
+
+// openTagEditor opens a form to edit the tag pairs as "Name: value" lines.
+func openTagEditor(g *gocui.Gui, v *gocui.View) error {
+	showTagEditor = true
+	maxX, maxY := g.Size()
+	width, height := 50, len(gameTags)+6
+	x0, y0 := max((maxX-width)/2, 0), max((maxY-height)/2, 0)
+	ev, err := g.SetView("tags", x0, y0, min(x0+width, maxX-1), min(y0+height, maxY-1))
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	ev.Title = "Edit tags - Ctrl+s: save, Esc: cancel"
+	ev.Editable = true
+	ev.Wrap = false
+	ev.Clear()
+	fmt.Fprint(ev, pgn.FormatTagLines(gameTags))
+	ev.SetCursor(0, 0)
+	g.SetCurrentView("tags")
+	g.Cursor = true
+	enableTagEditorKeybindings(g)
+	return nil
+}
+
+func closeTagEditor(g *gocui.Gui) {
+	showTagEditor = false
+	g.DeleteView("tags")
+	g.Cursor = false
+	g.SetCurrentView("board")
+	enableGlobalKeybindings(g, cfg.Keybindings)
+}
+
+// saveTags stores the tags entered in the form. Invalid lines keep the form open.
+func saveTags(g *gocui.Gui, v *gocui.View) error {
+	tags, err := pgn.ParseTagLines(v.Buffer())
+	if err != nil {
+		showInfoMessage(g, fmt.Sprintf("Invalid tags: %v", err))
+		return nil
+	}
+	// The seven tag roster is always kept
+	for _, name := range pgn.SevenTagRoster {
+		if pgn.TagValue(tags, name) == "" {
+			value := "?"
+			if name == "Result" {
+				value = "*"
+			}
+			tags = pgn.SetTag(tags, name, value)
+		}
+	}
+	gameTags = tags
+	autosave()
+	closeTagEditor(g)
+	showInfoMessage(g, "Tags updated.")
+	return nil
+}
+
+func cancelTagEditor(g *gocui.Gui, v *gocui.View) error {
+	closeTagEditor(g)
+	return nil
+}
+
+func tagEditorContext() keyContext {
+	return keyContext{name: "Tag editor", view: "tags", bindings: []binding{
+		{keys: []string{"Ctrl+s"}, help: "save the tags", handler: saveTags},
+		{keys: []string{"Esc", "Ctrl+q"}, help: "cancel", handler: cancelTagEditor},
+	}}
+}
+
+func enableTagEditorKeybindings(g *gocui.Gui) {
+	g.DeleteKeybindings("")
+	g.DeleteKeybindings("tags")
+	bindContext(g, tagEditorContext(), nil)
+}
+
+// renamePlayer replaces oldName in the White and Black tags, so that the
+// engine's player name follows difficulty changes.
+func renamePlayer(oldName, newName string) {
+	for _, name := range []string{"White", "Black"} {
+		if pgn.TagValue(gameTags, name) == oldName {
+			gameTags = pgn.SetTag(gameTags, name, newName)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/gui"
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/RubikNube/TerminalChess/pkg/theme"
+	"github.com/jroimartin/gocui"
+)
+
+var (
+	colorDepth = theme.Colors16
+	themeName  = theme.Default
+)
+
+// outputMode returns the gocui output mode for the configured colour depth,
+// which is detected from the terminal unless given in the config.
+func outputMode() gocui.OutputMode {
+	depth, err := theme.ParseDepth(cfg.Theme.Colors, os.Getenv)
+	if err != nil {
+		depth = theme.DetectDepth(os.Getenv)
+	}
+	colorDepth = depth
+	if depth == theme.Colors16 {
+		return gocui.OutputNormal
+	}
+	return gocui.Output256
+}
+
+// applyTheme draws the board with the colours of the named theme.
+func applyTheme(name string) error {
+	t, ok := theme.Lookup(name, cfg.Theme.Themes)
+	if !ok {
+		return fmt.Errorf("unknown theme %q", name)
+	}
+	palette, err := t.Palette(colorDepth)
+	if err != nil {
+		return fmt.Errorf("theme %s: %w", name, err)
+	}
+	gui.BoardPalette = palette
+	themeName = name
+	return nil
+}
+
+// cycleTheme switches to the next theme in alphabetical order.
+func cycleTheme(g *gocui.Gui, v *gocui.View) error {
+	names := theme.Names(cfg.Theme.Themes)
+	next := names[0]
+	for i, name := range names {
+		if name == themeName {
+			next = names[(i+1)%len(names)]
+		}
+	}
+	if err := applyTheme(next); err != nil {
+		showInfoMessage(g, err.Error())
+		return nil
+	}
+	showInfoMessage(g, "Theme: "+next)
+	return nil
+}
+
+func completeThemeCommand(arg int) []string {
+	if arg == 0 {
+		return theme.Names(cfg.Theme.Themes)
+	}
+	return nil
+}
+
+// runThemeCommand switches to the named theme, or lists the themes.
+func runThemeCommand(g *gocui.Gui, args []string) error {
+	if len(args) == 0 {
+		showInfoMessage(g, fmt.Sprintf("Theme: %s (available: %s)", themeName, strings.Join(theme.Names(cfg.Theme.Themes), ", ")))
+		return nil
+	}
+	if err := applyTheme(args[0]); err != nil {
+		showInfoMessage(g, err.Error())
+		return nil
+	}
+	showInfoMessage(g, "Theme: "+args[0])
+	return nil
+}
+
+// boardHighlights returns the squares of the last move leading to the shown
+// position b, and of the king it gives check to.
+func boardHighlights(b gui.ChessBoard) gui.Highlights {
+	ply := historyIndex
+	if ply < 0 {
+		ply = len(history.GetHistory()) - 1
+	}
+	record, ok := history.GetRecord(ply)
+	if !ok || !record.Legal() || len(record.UCI) < 4 {
+		return gui.Highlights{}
+	}
+	square := func(s string) gui.Square {
+		return gui.Square{Row: 8 - int(s[1]-'0'), Col: int(s[0] - 'a')}
+	}
+	h := gui.Highlights{LastMove: []gui.Square{square(record.UCI[:2]), square(record.UCI[2:4])}}
+	if record.Has(history.Check) {
+		checked := gui.White
+		if fields := strings.Fields(record.FEN); len(fields) > 1 && fields[1] == "b" {
+			checked = gui.Black
+		}
+		for row := range b {
+			for col, p := range b[row] {
+				if p.Type == gui.King && p.Color == checked {
+					h.Check = append(h.Check, gui.Square{Row: row, Col: col})
+				}
+			}
+		}
+	}
+	return h
+}
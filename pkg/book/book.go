@@ -0,0 +1,153 @@
+// Package book reads Polyglot opening books and looks up moves for positions.
+package book
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Selection modes for choosing a move from the book entries of a position.
+const (
+	SelectWeighted = "weighted" // Random move, weighted by the entry weights
+	SelectBest     = "best"     // Always the move with the highest weight
+)
+
+// Entry is a single book move for a position.
+type Entry struct {
+	Key    uint64
+	Move   string // UCI notation, castling as king move (e1g1)
+	Weight uint16
+	Learn  uint32
+}
+
+// Book is an in-memory Polyglot book with entries sorted by key.
+type Book struct {
+	Path    string
+	entries []Entry
+}
+
+// Open reads the Polyglot book at the given path.
+func Open(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read book %s: %w", path, err)
+	}
+	b.Path = path
+	return b, nil
+}
+
+// Read reads Polyglot book entries from r.
+func Read(r io.Reader) (*Book, error) {
+	b := &Book{}
+	br := bufio.NewReader(r)
+	var record [16]byte
+	for {
+		if _, err := io.ReadFull(br, record[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		b.entries = append(b.entries, Entry{
+			Key:    binary.BigEndian.Uint64(record[0:8]),
+			Move:   decodeMove(binary.BigEndian.Uint16(record[8:10])),
+			Weight: binary.BigEndian.Uint16(record[10:12]),
+			Learn:  binary.BigEndian.Uint32(record[12:16]),
+		})
+	}
+	// Books are sorted by key already, but keep the lookup safe for hand-made files
+	sort.SliceStable(b.entries, func(i, j int) bool { return b.entries[i].Key < b.entries[j].Key })
+	return b, nil
+}
+
+// Len returns the number of entries in the book.
+func (b *Book) Len() int {
+	return len(b.entries)
+}
+
+// Lookup returns the book entries for the position described by the FEN string.
+func (b *Book) Lookup(fen string) ([]Entry, error) {
+	key, err := Hash(fen)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(b.entries), func(i int) bool { return b.entries[i].Key >= key })
+	var entries []Entry
+	for ; i < len(b.entries) && b.entries[i].Key == key; i++ {
+		entry := b.entries[i]
+		entry.Move = castlingToKingMove(fen, entry.Move)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Choose picks one of the entries using the given selection mode. Entries with
+// zero weight are only chosen if no other entry is available.
+func Choose(entries []Entry, mode string) (Entry, bool) {
+	if len(entries) == 0 {
+		return Entry{}, false
+	}
+	best := entries[0]
+	total := 0
+	for _, e := range entries {
+		total += int(e.Weight)
+		if e.Weight > best.Weight {
+			best = e
+		}
+	}
+	if mode == SelectBest || total == 0 {
+		return best, true
+	}
+	n := rand.Intn(total)
+	for _, e := range entries {
+		n -= int(e.Weight)
+		if n < 0 {
+			return e, true
+		}
+	}
+	return best, true
+}
+
+// decodeMove converts a Polyglot move into UCI notation.
+func decodeMove(m uint16) string {
+	toFile := m & 7
+	toRow := (m >> 3) & 7
+	fromFile := (m >> 6) & 7
+	fromRow := (m >> 9) & 7
+	promo := (m >> 12) & 7
+	move := fmt.Sprintf("%c%d%c%d", 'a'+fromFile, fromRow+1, 'a'+toFile, toRow+1)
+	if promo > 0 {
+		move += string(" nbrq"[promo])
+	}
+	return move
+}
+
+// castlingToKingMove converts Polyglot castling moves (king takes own rook) into
+// the usual king move, e.g. e1h1 into e1g1.
+func castlingToKingMove(fen, move string) string {
+	castles := map[string]string{"e1h1": "e1g1", "e1a1": "e1c1", "e8h8": "e8g8", "e8a8": "e8c8"}
+	kingMove, ok := castles[move]
+	if !ok {
+		return move
+	}
+	board, err := parsePlacement(strings.Fields(fen)[0])
+	if err != nil {
+		return move
+	}
+	from := int(move[1]-'1')*8 + int(move[0]-'a')
+	if board[from] != 'K' && board[from] != 'k' {
+		return move
+	}
+	return kingMove
+}
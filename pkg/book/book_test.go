@@ -0,0 +1,97 @@
+package book
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// Reference keys from the Polyglot book format specification.
+func TestHash_ReferencePositions(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want uint64
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0x463b96181691fc9c},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 0x823c9b50fd114196},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", 0x0756b94461c50fb0},
+		{"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2", 0x662fafb965db29d4},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", 0x22a48b5a8e47ff78},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR b kq - 0 3", 0x652a607ca3f242c1},
+		{"rnbq1bnr/ppp1pkpp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR w - - 0 4", 0x00fdd303c946bdd9},
+		{"rnbqkbnr/p1pppppp/8/8/PpP4P/8/1P1PPPP1/RNBQKBNR b KQkq c3 0 3", 0x3c8123ea7b067637},
+		{"rnbqkbnr/p1pppppp/8/8/P6P/R1p5/1P1PPPP1/1NBQKBNR b Kkq - 0 4", 0x5c3f9b829b279560},
+	}
+	for _, tt := range tests {
+		got, err := Hash(tt.fen)
+		if err != nil {
+			t.Fatalf("Hash(%q) returned error: %v", tt.fen, err)
+		}
+		if got != tt.want {
+			t.Errorf("Hash(%q) = %016x, want %016x", tt.fen, got, tt.want)
+		}
+	}
+}
+
+func TestHash_InvalidFEN(t *testing.T) {
+	if _, err := Hash("not a fen"); err == nil {
+		t.Error("Expected error for invalid FEN")
+	}
+}
+
+// encodeEntry builds a 16 byte Polyglot record.
+func encodeEntry(key uint64, from, to string, weight uint16) []byte {
+	move := uint16(to[0]-'a') | uint16(to[1]-'1')<<3 | uint16(from[0]-'a')<<6 | uint16(from[1]-'1')<<9
+	rec := make([]byte, 16)
+	binary.BigEndian.PutUint64(rec[0:8], key)
+	binary.BigEndian.PutUint16(rec[8:10], move)
+	binary.BigEndian.PutUint16(rec[10:12], weight)
+	return rec
+}
+
+func TestLookup_FindsEntries(t *testing.T) {
+	start := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	startKey, _ := Hash(start)
+	var data []byte
+	data = append(data, encodeEntry(startKey, "e2", "e4", 10)...)
+	data = append(data, encodeEntry(startKey, "d2", "d4", 30)...)
+	data = append(data, encodeEntry(startKey+1, "g1", "f3", 5)...)
+
+	b, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if b.Len() != 3 {
+		t.Fatalf("Expected 3 entries, got %d", b.Len())
+	}
+	entries, err := b.Lookup(start)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries for start position, got %d", len(entries))
+	}
+	best, ok := Choose(entries, SelectBest)
+	if !ok || best.Move != "d2d4" {
+		t.Errorf("Expected best move d2d4, got %v", best)
+	}
+	if e, ok := Choose(entries, SelectWeighted); !ok || (e.Move != "d2d4" && e.Move != "e2e4") {
+		t.Errorf("Expected a book move, got %v", e)
+	}
+}
+
+func TestLookup_ConvertsCastling(t *testing.T) {
+	fen := "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1"
+	key, _ := Hash(fen)
+	b, _ := Read(bytes.NewReader(encodeEntry(key, "e1", "h1", 1)))
+	entries, _ := b.Lookup(fen)
+	if len(entries) != 1 || entries[0].Move != "e1g1" {
+		t.Errorf("Expected castling move e1g1, got %v", entries)
+	}
+}
+
+func TestChoose_Empty(t *testing.T) {
+	if _, ok := Choose(nil, SelectWeighted); ok {
+		t.Error("Expected no move for empty entries")
+	}
+}
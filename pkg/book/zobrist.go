@@ -0,0 +1,100 @@
+package book
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Offsets into randomTable for the non-piece keys.
+const (
+	castleOffset    = 768
+	enPassantOffset = 772
+	turnOffset      = 780
+)
+
+// pieceKinds maps FEN piece letters to the Polyglot piece index.
+var pieceKinds = map[rune]int{
+	'p': 0, 'P': 1,
+	'n': 2, 'N': 3,
+	'b': 4, 'B': 5,
+	'r': 6, 'R': 7,
+	'q': 8, 'Q': 9,
+	'k': 10, 'K': 11,
+}
+
+// Hash returns the Polyglot Zobrist key of the position described by the FEN string.
+func Hash(fen string) (uint64, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		return 0, fmt.Errorf("invalid FEN: %s", fen)
+	}
+	board, err := parsePlacement(fields[0])
+	if err != nil {
+		return 0, err
+	}
+
+	var key uint64
+	for sq, piece := range board {
+		if piece == 0 {
+			continue
+		}
+		rank, file := sq/8, sq%8
+		key ^= randomTable[64*pieceKinds[piece]+8*rank+file]
+	}
+
+	for i, c := range "KQkq" {
+		if strings.ContainsRune(fields[2], c) {
+			key ^= randomTable[castleOffset+i]
+		}
+	}
+
+	// The en passant file only counts if a pawn of the side to move can capture
+	if ep := fields[3]; ep != "-" && len(ep) == 2 {
+		file := int(ep[0] - 'a')
+		pawn, rank := 'P', 4 // White captures from the fifth rank
+		if fields[1] == "b" {
+			pawn, rank = 'p', 3 // Black captures from the fourth rank
+		}
+		for _, f := range []int{file - 1, file + 1} {
+			if f >= 0 && f < 8 && board[rank*8+f] == pawn {
+				key ^= randomTable[enPassantOffset+file]
+				break
+			}
+		}
+	}
+
+	if fields[1] == "w" {
+		key ^= randomTable[turnOffset]
+	}
+	return key, nil
+}
+
+// parsePlacement parses the piece placement field of a FEN string into a board
+// indexed by rank*8+file, with rank 0 being the first rank.
+func parsePlacement(placement string) ([64]rune, error) {
+	var board [64]rune
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return board, fmt.Errorf("invalid piece placement: %s", placement)
+	}
+	for i, row := range ranks {
+		rank := 7 - i
+		file := 0
+		for _, c := range row {
+			switch {
+			case c >= '1' && c <= '8':
+				file += int(c - '0')
+			default:
+				if _, ok := pieceKinds[c]; !ok || file > 7 {
+					return board, fmt.Errorf("invalid piece placement: %s", placement)
+				}
+				board[rank*8+file] = c
+				file++
+			}
+		}
+		if file != 8 {
+			return board, fmt.Errorf("invalid piece placement: %s", placement)
+		}
+	}
+	return board, nil
+}
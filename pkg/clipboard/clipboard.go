@@ -0,0 +1,137 @@
+// Package clipboard copies text to and pastes text from the system clipboard,
+// using the first available of several backends: wl-copy, xclip, xsel, pbcopy
+// and the OSC 52 terminal escape sequence, which also works inside tmux and
+// over SSH.
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Backend is a way to access the clipboard.
+type Backend interface {
+	Name() string
+	// Available reports whether the backend can be used in this environment.
+	Available() bool
+	Copy(text string) error
+	Paste() (string, error)
+}
+
+// ErrNoBackend is returned if none of the configured backends is available.
+var ErrNoBackend = errors.New("no clipboard backend available")
+
+// DefaultBackends is the order backends are tried in if none are configured.
+var DefaultBackends = []string{"wl-copy", "xclip", "xsel", "pbcopy", "osc52"}
+
+// commandBackend runs external programs to copy and paste.
+type commandBackend struct {
+	name  string
+	env   string // Environment variable that must be set, e.g. DISPLAY
+	copy  []string
+	paste []string
+}
+
+var commandBackends = map[string]commandBackend{
+	"wl-copy": {name: "wl-copy", env: "WAYLAND_DISPLAY", copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}},
+	"xclip":   {name: "xclip", env: "DISPLAY", copy: []string{"xclip", "-selection", "clipboard"}, paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+	"xsel":    {name: "xsel", env: "DISPLAY", copy: []string{"xsel", "--clipboard", "--input"}, paste: []string{"xsel", "--clipboard", "--output"}},
+	"pbcopy":  {name: "pbcopy", copy: []string{"pbcopy"}, paste: []string{"pbpaste"}},
+}
+
+func (b commandBackend) Name() string { return b.name }
+
+func (b commandBackend) Available() bool {
+	if b.env != "" && os.Getenv(b.env) == "" {
+		return false
+	}
+	_, err := exec.LookPath(b.copy[0])
+	return err == nil
+}
+
+func (b commandBackend) Copy(text string) error {
+	cmd := exec.Command(b.copy[0], b.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v %s", b.name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (b commandBackend) Paste() (string, error) {
+	out, err := exec.Command(b.paste[0], b.paste[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", b.paste[0], err)
+	}
+	return string(out), nil
+}
+
+// Clipboard tries its backends in order.
+type Clipboard struct {
+	backends []Backend
+}
+
+// New returns a clipboard using the named backends in the given order, or
+// DefaultBackends if names is empty. The terminal for OSC 52 is out.
+func New(names []string, out io.Writer) (*Clipboard, error) {
+	if len(names) == 0 {
+		names = DefaultBackends
+	}
+	c := &Clipboard{}
+	for _, name := range names {
+		if name == "osc52" {
+			c.backends = append(c.backends, OSC52{Out: out})
+			continue
+		}
+		b, ok := commandBackends[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown clipboard backend %q", name)
+		}
+		c.backends = append(c.backends, b)
+	}
+	return c, nil
+}
+
+// Copy copies text with the first available backend and returns its name. If
+// a backend fails, the next one is tried.
+func (c *Clipboard) Copy(text string) (string, error) {
+	var errs []error
+	for _, b := range c.backends {
+		if !b.Available() {
+			continue
+		}
+		err := b.Copy(text)
+		if err == nil {
+			return b.Name(), nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return "", ErrNoBackend
+	}
+	return "", errors.Join(errs...)
+}
+
+// Paste returns the clipboard content from the first available backend that
+// can paste.
+func (c *Clipboard) Paste() (string, error) {
+	var errs []error
+	for _, b := range c.backends {
+		if !b.Available() {
+			continue
+		}
+		text, err := b.Paste()
+		if err == nil {
+			return text, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return "", ErrNoBackend
+	}
+	return "", errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package clipboard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeBackend records copied text and fails if configured to.
+type fakeBackend struct {
+	name      string
+	available bool
+	fail      bool
+	copied    *string
+}
+
+func (f fakeBackend) Name() string    { return f.name }
+func (f fakeBackend) Available() bool { return f.available }
+
+func (f fakeBackend) Copy(text string) error {
+	if f.fail {
+		return errors.New(f.name + " failed")
+	}
+	*f.copied = text
+	return nil
+}
+
+func (f fakeBackend) Paste() (string, error) {
+	if f.fail {
+		return "", errors.New(f.name + " failed")
+	}
+	return *f.copied, nil
+}
+
+func TestCopy_FallsBackInOrder(t *testing.T) {
+	var copied string
+	c := &Clipboard{backends: []Backend{
+		fakeBackend{name: "missing", copied: &copied},
+		fakeBackend{name: "broken", available: true, fail: true, copied: &copied},
+		fakeBackend{name: "working", available: true, copied: &copied},
+	}}
+	name, err := c.Copy("1. e4 *")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if name != "working" || copied != "1. e4 *" {
+		t.Errorf("Expected text copied by working backend, got %q by %q", copied, name)
+	}
+	text, err := c.Paste()
+	if err != nil || text != "1. e4 *" {
+		t.Errorf("Expected pasted text, got %q, %v", text, err)
+	}
+}
+
+func TestCopy_NoBackend(t *testing.T) {
+	c := &Clipboard{}
+	if _, err := c.Copy("x"); !errors.Is(err, ErrNoBackend) {
+		t.Errorf("Expected ErrNoBackend, got %v", err)
+	}
+}
+
+func TestNew_OSC52(t *testing.T) {
+	var sb strings.Builder
+	c, err := New([]string{"osc52"}, &sb)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Setenv("TMUX", "")
+	if _, err := c.Copy("e4"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sb.String() != "\x1b]52;c;ZTQ=\x07" {
+		t.Errorf("Unexpected escape sequence %q", sb.String())
+	}
+	if _, err := New([]string{"carrier-pigeon"}, nil); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
+
+func TestOSC52Sequence_Tmux(t *testing.T) {
+	seq := osc52Sequence("e4", true)
+	if seq != "\x1bPtmux;\x1b\x1b]52;c;ZTQ=\x07\x1b\\" {
+		t.Errorf("Unexpected tmux sequence %q", seq)
+	}
+}
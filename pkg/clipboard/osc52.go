@@ -0,0 +1,45 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// OSC52 copies by sending the OSC 52 escape sequence to the terminal, which
+// sets the clipboard of the machine the terminal runs on. Reading the
+// clipboard this way is disabled in most terminals, so Paste is not supported.
+type OSC52 struct {
+	Out io.Writer // The terminal, os.Stdout if nil
+}
+
+func (OSC52) Name() string { return "osc52" }
+
+// Available reports true, as there is no way to ask the terminal for support.
+func (OSC52) Available() bool { return true }
+
+func (o OSC52) Copy(text string) error {
+	out := o.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	_, err := io.WriteString(out, osc52Sequence(text, os.Getenv("TMUX") != ""))
+	return err
+}
+
+func (OSC52) Paste() (string, error) {
+	return "", errors.New("osc52: paste is not supported")
+}
+
+// osc52Sequence returns the escape sequence setting the clipboard to text.
+// Inside tmux it is wrapped in a passthrough sequence.
+func osc52Sequence(text string, tmux bool) string {
+	seq := fmt.Sprintf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	if tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
@@ -0,0 +1,336 @@
+// Package diagram draws chess positions as PNG and SVG images, using only the
+// Go standard library.
+package diagram
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Arrow is an arrow drawn between two squares, e.g. from a [%cal] command.
+type Arrow struct {
+	From, To string // Squares in algebraic notation, e.g. "e2"
+	Color    color.RGBA
+}
+
+// Options control how a position is drawn.
+type Options struct {
+	Size        int      // Width and height of the board in pixels, 512 if zero
+	Flipped     bool     // Draw the board from Black's side
+	Coordinates bool     // Draw file and rank labels
+	LastMove    []string // Squares highlighted as the last move, e.g. ["e2", "e4"]
+	Arrows      []Arrow
+}
+
+var (
+	lightSquare = color.RGBA{0xf0, 0xd9, 0xb5, 0xff}
+	darkSquare  = color.RGBA{0xb5, 0x88, 0x63, 0xff}
+	lastMove    = color.RGBA{0xcd, 0xd2, 0x6a, 0xff}
+	outline     = color.RGBA{0x10, 0x10, 0x10, 0xff}
+	whitePiece  = color.RGBA{0xfa, 0xfa, 0xfa, 0xff}
+	blackPiece  = color.RGBA{0x30, 0x30, 0x30, 0xff}
+
+	// ArrowColors maps the colour letters of [%cal] and [%csl] commands to colours.
+	ArrowColors = map[byte]color.RGBA{
+		'G': {0x15, 0x78, 0x1b, 0xff},
+		'R': {0x88, 0x20, 0x20, 0xff},
+		'Y': {0xe6, 0x8f, 0x00, 0xff},
+		'B': {0x00, 0x30, 0x88, 0xff},
+	}
+)
+
+// ParseArrow parses an arrow in [%cal] form such as "Ge2e4". The colour letter
+// is optional and defaults to green.
+func ParseArrow(s string) (Arrow, error) {
+	c := ArrowColors['G']
+	if len(s) == 5 {
+		var ok bool
+		if c, ok = ArrowColors[s[0]]; !ok {
+			return Arrow{}, fmt.Errorf("unknown arrow colour %q", s[0])
+		}
+		s = s[1:]
+	}
+	if len(s) != 4 || !validSquare(s[:2]) || !validSquare(s[2:]) {
+		return Arrow{}, fmt.Errorf("invalid arrow %q", s)
+	}
+	return Arrow{From: s[:2], To: s[2:], Color: c}, nil
+}
+
+func validSquare(sq string) bool {
+	return len(sq) == 2 && sq[0] >= 'a' && sq[0] <= 'h' && sq[1] >= '1' && sq[1] <= '8'
+}
+
+// parsePlacement returns the pieces of the placement field of a FEN by rank
+// (0 = rank 8) and file. Empty squares are 0.
+func parsePlacement(fen string) ([8][8]rune, error) {
+	var squares [8][8]rune
+	fields := strings.Fields(fen)
+	if len(fields) == 0 {
+		return squares, fmt.Errorf("empty FEN")
+	}
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return squares, fmt.Errorf("invalid FEN %q: expected 8 ranks", fen)
+	}
+	for r, rank := range ranks {
+		file := 0
+		for _, c := range rank {
+			switch {
+			case c >= '1' && c <= '8':
+				file += int(c - '0')
+			case strings.ContainsRune("pnbrqkPNBRQK", c):
+				if file < 8 {
+					squares[r][file] = c
+				}
+				file++
+			default:
+				return squares, fmt.Errorf("invalid FEN %q: unexpected %q", fen, c)
+			}
+		}
+		if file != 8 {
+			return squares, fmt.Errorf("invalid FEN %q: rank %d has %d files", fen, 8-r, file)
+		}
+	}
+	return squares, nil
+}
+
+// layout holds the geometry shared by the PNG and SVG renderers.
+type layout struct {
+	opts   Options
+	square int // Square size in pixels
+	size   int // Board size in pixels
+}
+
+func newLayout(opts Options) layout {
+	if opts.Size <= 0 {
+		opts.Size = 512
+	}
+	square := max(opts.Size/8, 16)
+	return layout{opts: opts, square: square, size: square * 8}
+}
+
+// origin returns the top left pixel of the square at rank (0 = rank 8) and file.
+func (l layout) origin(rank, file int) (int, int) {
+	if l.opts.Flipped {
+		rank, file = 7-rank, 7-file
+	}
+	return file * l.square, rank * l.square
+}
+
+// center returns the centre pixel of a square given in algebraic notation.
+func (l layout) center(sq string) (float64, float64) {
+	x, y := l.origin(int('8'-sq[1]), int(sq[0]-'a'))
+	return float64(x) + float64(l.square)/2, float64(y) + float64(l.square)/2
+}
+
+func (l layout) highlighted(rank, file int) bool {
+	name := string(rune('a'+file)) + string(rune('8'-rank))
+	for _, sq := range l.opts.LastMove {
+		if sq == name {
+			return true
+		}
+	}
+	return false
+}
+
+// squareColor returns the colour of the square at rank (0 = rank 8) and file.
+func (l layout) squareColor(rank, file int) color.RGBA {
+	c := lightSquare
+	if (rank+file)%2 == 1 {
+		c = darkSquare
+	}
+	if l.highlighted(rank, file) {
+		c = blend(c, lastMove, 0.6)
+	}
+	return c
+}
+
+// pieceColors returns the fill and detail colours of a piece.
+func pieceColors(piece rune) (fill, detail color.RGBA) {
+	if unicode.IsUpper(piece) {
+		return whitePiece, outline
+	}
+	return blackPiece, whitePiece
+}
+
+// label is a coordinate drawn on the square at rank (0 = rank 8) and file.
+type label struct {
+	text       rune
+	rank, file int
+}
+
+// labels returns the coordinate labels. Files are drawn on the bottom rank and
+// ranks on the left file.
+func (l layout) labels() []label {
+	var labels []label
+	bottom, left := 7, 0
+	if l.opts.Flipped {
+		bottom, left = 0, 7
+	}
+	for i := 0; i < 8; i++ {
+		labels = append(labels, label{rune('a' + i), bottom, i}, label{rune('8' - i), i, left})
+	}
+	return labels
+}
+
+// Render draws the position of fen.
+func Render(fen string, opts Options) (*image.RGBA, error) {
+	squares, err := parsePlacement(fen)
+	if err != nil {
+		return nil, err
+	}
+	l := newLayout(opts)
+	img := image.NewRGBA(image.Rect(0, 0, l.size, l.size))
+	for rank := 0; rank < 8; rank++ {
+		for file := 0; file < 8; file++ {
+			x, y := l.origin(rank, file)
+			fillRect(img, x, y, l.square, l.square, l.squareColor(rank, file))
+			if piece := squares[rank][file]; piece != 0 {
+				drawSprite(img, x, y, l.square, piece)
+			}
+		}
+	}
+	if opts.Coordinates {
+		for _, label := range l.labels() {
+			drawLabel(img, l, label.text, label.rank, label.file)
+		}
+	}
+	for _, a := range opts.Arrows {
+		drawArrow(img, l, a)
+	}
+	return img, nil
+}
+
+// WritePNG writes the position of fen as a PNG image.
+func WritePNG(w io.Writer, fen string, opts Options) error {
+	img, err := Render(fen, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+func fillRect(img *image.RGBA, x, y, w, h int, c color.RGBA) {
+	for py := y; py < y+h; py++ {
+		for px := x; px < x+w; px++ {
+			img.SetRGBA(px, py, c)
+		}
+	}
+}
+
+// drawSprite draws the pixel art of piece scaled to a square at x, y.
+func drawSprite(img *image.RGBA, x, y, square int, piece rune) {
+	sprite := sprites[unicode.ToLower(piece)]
+	fill, detail := pieceColors(piece)
+	for py := 0; py < square; py++ {
+		row := sprite[py*16/square]
+		for px := 0; px < square; px++ {
+			switch row[px*16/square] {
+			case 'x':
+				img.SetRGBA(x+px, y+py, outline)
+			case '#':
+				img.SetRGBA(x+px, y+py, fill)
+			case 'o':
+				img.SetRGBA(x+px, y+py, detail)
+			}
+		}
+	}
+}
+
+// drawLabel draws a coordinate in the corner of a square, in the colour of the
+// other squares so it stays readable.
+func drawLabel(img *image.RGBA, l layout, text rune, rank, file int) {
+	x, y := l.origin(rank, file)
+	scale := max(l.square/24, 1)
+	c := lightSquare
+	if (rank+file)%2 == 0 {
+		c = darkSquare
+	}
+	if unicode.IsDigit(text) {
+		// Ranks in the top left corner
+		x, y = x+scale, y+scale
+	} else {
+		// Files in the bottom right corner
+		x, y = x+l.square-4*scale, y+l.square-6*scale
+	}
+	for gy, row := range glyphs[text] {
+		for gx, bit := range row {
+			if bit == '#' {
+				fillRect(img, x+gx*scale, y+gy*scale, scale, scale, c)
+			}
+		}
+	}
+}
+
+// arrowGeometry returns the shaft end and the three corners of the head of an arrow.
+func arrowGeometry(l layout, a Arrow) (x0, y0, x1, y1 float64, head [3][2]float64) {
+	x0, y0 = l.center(a.From)
+	tx, ty := l.center(a.To)
+	dx, dy := tx-x0, ty-y0
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return x0, y0, x0, y0, head
+	}
+	ux, uy := dx/length, dy/length
+	headLength, headWidth := float64(l.square)*0.45, float64(l.square)*0.3
+	// The head ends short of the centre of the target square
+	tipX, tipY := tx-ux*float64(l.square)*0.15, ty-uy*float64(l.square)*0.15
+	x1, y1 = tipX-ux*headLength, tipY-uy*headLength
+	head = [3][2]float64{
+		{tipX, tipY},
+		{x1 - uy*headWidth, y1 + ux*headWidth},
+		{x1 + uy*headWidth, y1 - ux*headWidth},
+	}
+	return x0, y0, x1, y1, head
+}
+
+// drawArrow blends a semi transparent arrow into img.
+func drawArrow(img *image.RGBA, l layout, a Arrow) {
+	if a.From == a.To {
+		return
+	}
+	x0, y0, x1, y1, head := arrowGeometry(l, a)
+	halfWidth := float64(l.square) * 0.1
+	for py := 0; py < l.size; py++ {
+		for px := 0; px < l.size; px++ {
+			x, y := float64(px)+0.5, float64(py)+0.5
+			if segmentDistance(x, y, x0, y0, x1, y1) <= halfWidth || inTriangle(x, y, head) {
+				img.SetRGBA(px, py, blend(img.RGBAAt(px, py), a.Color, 0.8))
+			}
+		}
+	}
+}
+
+// segmentDistance returns the distance of x, y from the segment x0,y0 - x1,y1.
+func segmentDistance(x, y, x0, y0, x1, y1 float64) float64 {
+	dx, dy := x1-x0, y1-y0
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, ((x-x0)*dx+(y-y0)*dy)/lengthSq))
+	}
+	return math.Hypot(x-(x0+t*dx), y-(y0+t*dy))
+}
+
+func inTriangle(x, y float64, t [3][2]float64) bool {
+	side := func(a, b [2]float64) float64 {
+		return (x-b[0])*(a[1]-b[1]) - (a[0]-b[0])*(y-b[1])
+	}
+	d1, d2, d3 := side(t[0], t[1]), side(t[1], t[2]), side(t[2], t[0])
+	negative := d1 < 0 || d2 < 0 || d3 < 0
+	positive := d1 > 0 || d2 > 0 || d3 > 0
+	return !(negative && positive)
+}
+
+// blend mixes c over base with the given opacity.
+func blend(base, c color.RGBA, alpha float64) color.RGBA {
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*(1-alpha) + float64(b)*alpha + 0.5)
+	}
+	return color.RGBA{mix(base.R, c.R), mix(base.G, c.G), mix(base.B, c.B), 0xff}
+}
@@ -0,0 +1,105 @@
+package diagram
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func TestWritePNG_Size(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, startFEN, Options{Size: 256, Coordinates: true}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Expected a valid PNG, got %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 256 || b.Dy() != 256 {
+		t.Errorf("Expected 256x256 image, got %v", b)
+	}
+}
+
+func TestRender_FlippedAndLastMove(t *testing.T) {
+	opts := Options{Size: 128, LastMove: []string{"a1"}}
+	normal, err := Render(startFEN, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	opts.Flipped = true
+	flipped, err := Render(startFEN, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// a1 is the bottom left square, or the top right one when flipped. Its
+	// top left pixel is not covered by the rook.
+	want := blend(darkSquare, lastMove, 0.6)
+	if got := normal.RGBAAt(0, 112); got != want {
+		t.Errorf("Expected highlighted a1 at the bottom left, got %v", got)
+	}
+	if got := flipped.RGBAAt(112, 0); got != want {
+		t.Errorf("Expected highlighted a1 at the top right when flipped, got %v", got)
+	}
+}
+
+func TestWriteSVG_PiecesAndArrows(t *testing.T) {
+	arrow, err := ParseArrow("Re2e4")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var sb strings.Builder
+	if err := WriteSVG(&sb, startFEN, Options{Arrows: []Arrow{arrow}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	svg := sb.String()
+	if n := strings.Count(svg, "<use "); n != 32 {
+		t.Errorf("Expected 32 pieces, got %d", n)
+	}
+	if !strings.Contains(svg, "<polygon") || !strings.Contains(svg, hex(ArrowColors['R'])) {
+		t.Error("Expected a red arrow")
+	}
+}
+
+func TestParseArrow_Invalid(t *testing.T) {
+	for _, s := range []string{"e2e9", "Xe2e4", "e2"} {
+		if _, err := ParseArrow(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+	if _, err := Render("not a fen", Options{}); err == nil {
+		t.Error("Expected error for invalid FEN")
+	}
+}
+
+func TestWriteGIF_Frames(t *testing.T) {
+	frames := []Frame{
+		{FEN: startFEN},
+		{FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", LastMove: []string{"e2", "e4"}},
+		{FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", LastMove: []string{"e7", "e5"}},
+	}
+	var buf bytes.Buffer
+	if err := WriteGIF(&buf, frames, GIFOptions{Options: Options{Size: 128}, Delay: 500 * time.Millisecond}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("Expected a valid GIF, got %v", err)
+	}
+	if len(anim.Image) != 3 {
+		t.Fatalf("Expected 3 frames, got %d", len(anim.Image))
+	}
+	if anim.Delay[0] != 50 || anim.Delay[2] != 300 {
+		t.Errorf("Expected delays of 50 and 300 centiseconds, got %v", anim.Delay)
+	}
+	// The exact square colours survive the palette conversion
+	r, g, b, _ := anim.Image[1].At(0, 127).RGBA()
+	if (color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xff}) != darkSquare {
+		t.Errorf("Expected dark a1 square, got %v %v %v", r>>8, g>>8, b>>8)
+	}
+}
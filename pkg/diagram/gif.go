@@ -0,0 +1,80 @@
+package diagram
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// Frame is one position of an animation.
+type Frame struct {
+	FEN      string
+	LastMove []string // Squares highlighted as the last move
+	Arrows   []Arrow
+}
+
+// GIFOptions control an animation. The board options apply to every frame.
+type GIFOptions struct {
+	Options
+	Delay      time.Duration // Time each position is shown, one second if zero
+	FinalDelay time.Duration // Time the last position is shown, three seconds if zero
+}
+
+// WriteGIF writes the frames as an animated GIF that loops forever.
+func WriteGIF(w io.Writer, frames []Frame, opts GIFOptions) error {
+	if opts.Delay <= 0 {
+		opts.Delay = time.Second
+	}
+	if opts.FinalDelay <= 0 {
+		opts.FinalDelay = 3 * time.Second
+	}
+	images := make([]*image.RGBA, len(frames))
+	for i, f := range frames {
+		frameOpts := opts.Options
+		frameOpts.LastMove, frameOpts.Arrows = f.LastMove, f.Arrows
+		img, err := Render(f.FEN, frameOpts)
+		if err != nil {
+			return err
+		}
+		images[i] = img
+	}
+
+	pal := framePalette(images)
+	anim := &gif.GIF{}
+	for i, img := range images {
+		paletted := image.NewPaletted(img.Bounds(), pal)
+		draw.Draw(paletted, img.Bounds(), img, image.Point{}, draw.Src)
+		delay := opts.Delay
+		if i == len(images)-1 {
+			delay = opts.FinalDelay
+		}
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, int(delay/(10*time.Millisecond)))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// framePalette returns the colours used by the images, or the web safe palette
+// if they use more than a GIF can hold, e.g. because of blended arrows.
+func framePalette(images []*image.RGBA) color.Palette {
+	seen := map[color.RGBA]bool{}
+	var pal color.Palette
+	for _, img := range images {
+		for i := 0; i+3 < len(img.Pix); i += 4 {
+			c := color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
+			if seen[c] {
+				continue
+			}
+			if len(pal) == 256 {
+				return palette.WebSafe
+			}
+			seen[c] = true
+			pal = append(pal, c)
+		}
+	}
+	return pal
+}
@@ -0,0 +1,134 @@
+package diagram
+
+// Pixel art of the pieces on a 16x16 grid: '#' is the piece colour, 'x' the
+// outline and 'o' details drawn in the opposite colour.
+var sprites = map[rune][]string{
+	'p': {
+		"................",
+		"................",
+		"................",
+		"......xxxx......",
+		".....x####x.....",
+		".....x####x.....",
+		"......x##x......",
+		".....x####x.....",
+		"......x##x......",
+		"......x##x......",
+		".....x####x.....",
+		"....x######x....",
+		"...x########x...",
+		"...xxxxxxxxxx...",
+		"................",
+		"................",
+	},
+	'r': {
+		"................",
+		"..xxx.xxxx.xxx..",
+		"..x#x.x##x.x#x..",
+		"..x#xxx##xxx#x..",
+		"..x##########x..",
+		"..xxxxxxxxxxxx..",
+		"...x########x...",
+		"...x########x...",
+		"...x########x...",
+		"...x########x...",
+		"...x########x...",
+		"..xxxxxxxxxxxx..",
+		".x############x.",
+		".xxxxxxxxxxxxxx.",
+		"................",
+		"................",
+	},
+	'n': {
+		"................",
+		"......x.x.......",
+		".....x#x#x......",
+		"....x#####xx....",
+		"...x###o####x...",
+		"..x#########x...",
+		".x####xx####x...",
+		".xx##x.x####x...",
+		"..xxx.x####x....",
+		".....x####x.....",
+		"....x#####x.....",
+		"....x######x....",
+		"...x########x...",
+		"...xxxxxxxxxx...",
+		"................",
+		"................",
+	},
+	'b': {
+		"................",
+		".......xx.......",
+		"......x##x......",
+		".....x####x.....",
+		"....x##o###x....",
+		"....x#o####x....",
+		"....x######x....",
+		".....x####x.....",
+		"......x##x......",
+		".....xxxxxx.....",
+		".....x####x.....",
+		"....x######x....",
+		"...x########x...",
+		"...xxxxxxxxxx...",
+		"................",
+		"................",
+	},
+	'q': {
+		"................",
+		".x....x..x....x.",
+		"x#x..x#xx#x..x#x",
+		".x#x.x#xx#x.x#x.",
+		".x##xx####xx##x.",
+		"..x##########x..",
+		"..x##########x..",
+		"...x########x...",
+		"...x########x...",
+		"....xxxxxxxx....",
+		"....x######x....",
+		"...x########x...",
+		"..x##########x..",
+		"..xxxxxxxxxxxx..",
+		"................",
+		"................",
+	},
+	'k': {
+		".......xx.......",
+		"......x##x......",
+		".....xx##xx.....",
+		".....x####x.....",
+		".....xx##xx.....",
+		"..xxx.x##x.xxx..",
+		".x###x####x###x.",
+		"x#####x##x#####x",
+		"x######xx######x",
+		".x############x.",
+		"..x##########x..",
+		"...x########x...",
+		"...xxxxxxxxxx...",
+		"...x########x...",
+		"...xxxxxxxxxx...",
+		"................",
+	},
+}
+
+// glyphs is a 3x5 pixel font for the board coordinates.
+var glyphs = map[rune][]string{
+	'a': {"###", "..#", "###", "#.#", "###"},
+	'b': {"#..", "#..", "###", "#.#", "###"},
+	'c': {"...", "###", "#..", "#..", "###"},
+	'd': {"..#", "..#", "###", "#.#", "###"},
+	'e': {"###", "#.#", "###", "#..", "###"},
+	'f': {".##", "#..", "###", "#..", "#.."},
+	'g': {"###", "#.#", "###", "..#", "###"},
+	'h': {"#..", "#..", "###", "#.#", "#.#"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", ".##", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", ".#.", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+}
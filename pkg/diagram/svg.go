@@ -0,0 +1,104 @@
+package diagram
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// WriteSVG writes the position of fen as an SVG image. Pieces use the same
+// pixel art as the PNG output, so both look alike.
+func WriteSVG(w io.Writer, fen string, opts Options) error {
+	squares, err := parsePlacement(fen)
+	if err != nil {
+		return err
+	}
+	l := newLayout(opts)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", l.size, l.size, l.size, l.size)
+
+	// One symbol per piece, scaled to the square size when used
+	fmt.Fprintln(bw, "<defs>")
+	for _, piece := range "PNBRQKpnbrqk" {
+		fmt.Fprintf(bw, `<symbol id="%s" viewBox="0 0 16 16" shape-rendering="crispEdges">`, pieceID(piece))
+		fill, detail := pieceColors(piece)
+		for y, row := range sprites[unicode.ToLower(piece)] {
+			writeRuns(bw, row, y, 'x', outline)
+			writeRuns(bw, row, y, '#', fill)
+			writeRuns(bw, row, y, 'o', detail)
+		}
+		fmt.Fprintln(bw, "</symbol>")
+	}
+	fmt.Fprintln(bw, "</defs>")
+
+	for rank := 0; rank < 8; rank++ {
+		for file := 0; file < 8; file++ {
+			x, y := l.origin(rank, file)
+			fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", x, y, l.square, l.square, hex(l.squareColor(rank, file)))
+			if piece := squares[rank][file]; piece != 0 {
+				fmt.Fprintf(bw, `<use xlink:href="#%s" href="#%s" x="%d" y="%d" width="%d" height="%d"/>`+"\n", pieceID(piece), pieceID(piece), x, y, l.square, l.square)
+			}
+		}
+	}
+
+	if opts.Coordinates {
+		fontSize := max(l.square/5, 8)
+		for _, lb := range l.labels() {
+			x, y := l.origin(lb.rank, lb.file)
+			c := lightSquare
+			if (lb.rank+lb.file)%2 == 0 {
+				c = darkSquare
+			}
+			anchor := "start"
+			if unicode.IsDigit(lb.text) {
+				x, y = x+fontSize/4, y+fontSize
+			} else {
+				anchor = "end"
+				x, y = x+l.square-fontSize/4, y+l.square-fontSize/4
+			}
+			fmt.Fprintf(bw, `<text x="%d" y="%d" font-family="sans-serif" font-weight="bold" font-size="%d" text-anchor="%s" fill="%s">%c</text>`+"\n", x, y, fontSize, anchor, hex(c), lb.text)
+		}
+	}
+
+	for _, a := range opts.Arrows {
+		if a.From == a.To {
+			continue
+		}
+		x0, y0, x1, y1, head := arrowGeometry(l, a)
+		fmt.Fprintf(bw, `<g fill="%s" stroke="%s" opacity="0.8"><line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke-width="%.1f" stroke-linecap="round"/>`, hex(a.Color), hex(a.Color), x0, y0, x1, y1, float64(l.square)*0.2)
+		fmt.Fprintf(bw, `<polygon stroke="none" points="%.1f,%.1f %.1f,%.1f %.1f,%.1f"/></g>`+"\n", head[0][0], head[0][1], head[1][0], head[1][1], head[2][0], head[2][1])
+	}
+
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// writeRuns writes one rect per horizontal run of pixel in row y of a sprite.
+func writeRuns(w io.Writer, row string, y int, pixel byte, c color.RGBA) {
+	for x := 0; x < len(row); x++ {
+		if row[x] != pixel {
+			continue
+		}
+		end := x
+		for end < len(row) && row[end] == pixel {
+			end++
+		}
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="1" fill="%s"/>`, x, y, end-x, hex(c))
+		x = end
+	}
+}
+
+// pieceID returns the SVG id of a piece, e.g. "wN" or "bq".
+func pieceID(piece rune) string {
+	if unicode.IsUpper(piece) {
+		return "w" + string(piece)
+	}
+	return "b" + strings.ToUpper(string(piece))
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
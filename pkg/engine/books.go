@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"log"
+
+	"github.com/RubikNube/TerminalChess/pkg/book"
+	"github.com/corentings/chess"
+)
+
+var openingBooks []*book.Book // Books loaded from the engine configuration, in priority order
+
+// loadBooks opens the configured Polyglot books. Books that cannot be read are
+// skipped so the engine stays usable without them.
+func loadBooks(paths []string) {
+	openingBooks = nil
+	for _, path := range paths {
+		b, err := book.Open(path)
+		if err != nil {
+			log.Printf("Failed to load opening book %s: %v", path, err)
+			continue
+		}
+		log.Printf("Loaded opening book %s with %d entries", path, b.Len())
+		openingBooks = append(openingBooks, b)
+	}
+}
+
+// BookMove returns a move from the opening books for the given FEN position,
+// or false if the position is not in any book or ply exceeds the book depth.
+func BookMove(fen string, ply int) (string, bool) {
+	if LoadedEngineConfig.BookDepth > 0 && ply >= LoadedEngineConfig.BookDepth {
+		return "", false
+	}
+	fenFunc, err := chess.FEN(fen)
+	if err != nil {
+		return "", false
+	}
+	pos := chess.NewGame(fenFunc).Position()
+	for _, b := range openingBooks {
+		entries, err := b.Lookup(fen)
+		if err != nil {
+			return "", false
+		}
+		// Guard against hash collisions by only keeping legal moves
+		var legal []book.Entry
+		for _, e := range entries {
+			if _, err := (chess.UCINotation{}).Decode(pos, e.Move); err == nil {
+				legal = append(legal, e)
+			}
+		}
+		if entry, ok := book.Choose(legal, LoadedEngineConfig.BookSelection); ok {
+			return entry.Move, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/corentings/chess"
+)
+
+// Difficulty is a named engine strength preset. It bundles the UCI strength
+// options with search limits and an optional chance of playing a random move.
+type Difficulty struct {
+	Name             string  `json:"name"`
+	Elo              int     `json:"elo"`              // Nominal strength, sent as UCI_Elo if LimitStrength is set
+	LimitStrength    bool    `json:"limitStrength"`    // Enables UCI_LimitStrength
+	SkillLevel       int     `json:"skillLevel"`       // Stockfish "Skill Level" (0-20)
+	Depth            int     `json:"depth"`            // Maximum search depth, 0 means no depth limit
+	MoveTime         int     `json:"moveTime"`         // Maximum think time in milliseconds, 0 means no time limit
+	RandomMoveChance float64 `json:"randomMoveChance"` // Probability (0-1) of playing a random legal move
+}
+
+// DifficultyPresets are the built-in levels, ordered from weakest to strongest.
+// They can be replaced by a "difficulties" list in engine.json.
+var DifficultyPresets = []Difficulty{
+	{Name: "beginner", Elo: 800, SkillLevel: 0, Depth: 1, MoveTime: 50, RandomMoveChance: 0.3},
+	{Name: "novice", Elo: 1100, SkillLevel: 3, Depth: 3, MoveTime: 100, RandomMoveChance: 0.15},
+	{Name: "casual", Elo: 1400, LimitStrength: true, SkillLevel: 6, Depth: 6, MoveTime: 200, RandomMoveChance: 0.05},
+	{Name: "club", Elo: 1700, LimitStrength: true, SkillLevel: 10, Depth: 8, MoveTime: 400},
+	{Name: "expert", Elo: 2000, LimitStrength: true, SkillLevel: 14, Depth: 12, MoveTime: 800},
+	{Name: "master", Elo: 2400, LimitStrength: true, SkillLevel: 18, Depth: 16, MoveTime: 1500},
+	{Name: "maximum", SkillLevel: 20, Depth: 22, MoveTime: 3000},
+}
+
+// defaultDepth is the search depth used when no difficulty is selected.
+const defaultDepth = 10
+
+var currentDifficulty = -1 // Index into Difficulties(), -1 means none selected
+
+// Search limits set at runtime, overriding the configured depth and the limits
+// of the difficulty level. 0 means not set.
+var depthLimit, moveTimeLimit int
+
+// SetSearchLimits overrides the search depth and move time in milliseconds of
+// engine moves. A zero value restores the configured limit.
+func SetSearchLimits(depth, moveTime int) {
+	depthLimit, moveTimeLimit = depth, moveTime
+}
+
+// SearchLimits returns the limits set with SetSearchLimits.
+func SearchLimits() (depth, moveTime int) {
+	return depthLimit, moveTimeLimit
+}
+
+// Difficulties returns the available difficulty levels.
+func Difficulties() []Difficulty {
+	if len(LoadedEngineConfig.Difficulties) > 0 {
+		return LoadedEngineConfig.Difficulties
+	}
+	return DifficultyPresets
+}
+
+// CurrentDifficulty returns the selected difficulty level, if any.
+func CurrentDifficulty() (Difficulty, bool) {
+	levels := Difficulties()
+	if currentDifficulty < 0 || currentDifficulty >= len(levels) {
+		return Difficulty{}, false
+	}
+	return levels[currentDifficulty], true
+}
+
+// SetDifficulty selects the difficulty level with the given name and applies it
+// to the running engine.
+func SetDifficulty(name string) error {
+	for i, level := range Difficulties() {
+		if strings.EqualFold(level.Name, name) {
+			applyDifficulty(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown difficulty: %s", name)
+}
+
+// StepDifficulty moves the selected level up (step > 0) or down (step < 0),
+// clamped to the weakest and strongest level, and returns the new level.
+func StepDifficulty(step int) Difficulty {
+	levels := Difficulties()
+	index := currentDifficulty
+	if index < 0 {
+		// Nothing selected yet: start from the level closest to the configured Elo
+		index = closestDifficulty(levels, configuredElo())
+	} else {
+		index += step
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(levels) {
+		index = len(levels) - 1
+	}
+	applyDifficulty(index)
+	return levels[index]
+}
+
+// applyDifficulty stores the level as current, mirrors it into the loaded
+// options and sends the strength options to the engine if it is running.
+func applyDifficulty(index int) {
+	currentDifficulty = index
+	level := Difficulties()[index]
+	SetOption("Skill Level", level.SkillLevel)
+	SetOption("UCI_LimitStrength", level.LimitStrength)
+	if level.LimitStrength {
+		SetOption("UCI_Elo", level.Elo)
+	} else {
+		// Keep the nominal Elo in the options without limiting the engine
+		LoadedEngineConfig.Options["UCI_Elo"] = level.Elo
+	}
+	if loadedEngine != nil {
+		SendCommand("isready")
+		readUntil("readyok")
+	}
+}
+
+// configuredElo returns the UCI_Elo option from the engine configuration.
+func configuredElo() int {
+	switch v := LoadedEngineConfig.Options["UCI_Elo"].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+func closestDifficulty(levels []Difficulty, elo int) int {
+	best := len(levels) - 1
+	for i, level := range levels {
+		if abs(level.Elo-elo) < abs(levels[best].Elo-elo) {
+			best = i
+		}
+	}
+	return best
+}
+
+// GetMove returns the engine move for the given FEN position, honouring the
+// search limits and random move chance of the selected difficulty. Limits set
+// with SetSearchLimits take precedence.
+func GetMove(fen string) (string, error) {
+	level, ok := CurrentDifficulty()
+	if !ok {
+		depth := LoadedEngineConfig.Depth
+		if depth <= 0 {
+			depth = defaultDepth
+		}
+		level = Difficulty{Depth: depth}
+	}
+	if level.RandomMoveChance > 0 && rand.Float64() < level.RandomMoveChance {
+		if move, err := randomMove(fen); err == nil {
+			return move, nil
+		}
+	}
+	if depthLimit > 0 || moveTimeLimit > 0 {
+		return GetBestMoveWithLimits(fen, depthLimit, moveTimeLimit)
+	}
+	return GetBestMoveWithLimits(fen, level.Depth, level.MoveTime)
+}
+
+// randomMove returns a random legal move in UCI notation.
+func randomMove(fen string) (string, error) {
+	fenFunc, err := chess.FEN(fen)
+	if err != nil {
+		return "", err
+	}
+	game := chess.NewGame(fenFunc)
+	moves := game.ValidMoves()
+	if len(moves) == 0 {
+		return "", fmt.Errorf("no legal moves")
+	}
+	move := moves[rand.Intn(len(moves))]
+	return chess.UCINotation{}.Encode(game.Position(), move), nil
+}
+
+// PlayerName returns the engine's name for PGN player tags, including the
+// selected difficulty and Elo. The Elo is left out if the level does not limit
+// the strength to it, e.g. "stockfish (Maximum)".
+func PlayerName() string {
+	if level, ok := CurrentDifficulty(); ok {
+		return fmt.Sprintf("%s (%s)", LoadedEngineConfig.Name, Label(level))
+	}
+	if elo := configuredElo(); elo > 0 {
+		return fmt.Sprintf("%s (Elo: %d)", LoadedEngineConfig.Name, elo)
+	}
+	return LoadedEngineConfig.Name
+}
+
+// Label returns the display name of the level with its Elo, e.g.
+// "Club, Elo: 1700", or only the name for levels that don't limit the
+// strength to their Elo.
+func Label(level Difficulty) string {
+	if !level.LimitStrength {
+		return DisplayName(level)
+	}
+	return fmt.Sprintf("%s, Elo: %d", DisplayName(level), level.Elo)
+}
+
+// DisplayName returns the level name with its first letter capitalised.
+func DisplayName(level Difficulty) string {
+	if level.Name == "" {
+		return ""
+	}
+	return strings.ToUpper(level.Name[:1]) + level.Name[1:]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
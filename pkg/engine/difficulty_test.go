@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/corentings/chess"
+)
+
+func TestSetDifficulty_UpdatesOptions(t *testing.T) {
+	LoadedEngineConfig = EngineConfig{Name: "stockfish"}
+	if err := SetDifficulty("Club"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	level, ok := CurrentDifficulty()
+	if !ok || level.Name != "club" {
+		t.Fatalf("Expected club to be selected, got %v", level)
+	}
+	if LoadedEngineConfig.Options["UCI_Elo"] != 1700 {
+		t.Errorf("Expected UCI_Elo 1700, got %v", LoadedEngineConfig.Options["UCI_Elo"])
+	}
+	if got := PlayerName(); got != "stockfish (Club, Elo: 1700)" {
+		t.Errorf("Unexpected player name: %s", got)
+	}
+}
+
+func TestSetDifficulty_Unknown(t *testing.T) {
+	LoadedEngineConfig = EngineConfig{Name: "stockfish"}
+	if err := SetDifficulty("grandmaster"); err == nil {
+		t.Error("Expected error for unknown difficulty")
+	}
+}
+
+func TestStepDifficulty_Clamps(t *testing.T) {
+	LoadedEngineConfig = EngineConfig{Name: "stockfish"}
+	SetDifficulty("beginner")
+	if level := StepDifficulty(-1); level.Name != "beginner" {
+		t.Errorf("Expected to stay at beginner, got %s", level.Name)
+	}
+	SetDifficulty("maximum")
+	if level := StepDifficulty(1); level.Name != "maximum" {
+		t.Errorf("Expected to stay at maximum, got %s", level.Name)
+	}
+	if level := StepDifficulty(-1); level.Name != "master" {
+		t.Errorf("Expected master, got %s", level.Name)
+	}
+}
+
+func TestStepDifficulty_StartsFromConfiguredElo(t *testing.T) {
+	currentDifficulty = -1
+	LoadedEngineConfig = EngineConfig{Name: "stockfish", Options: map[string]interface{}{"UCI_Elo": float64(1950)}}
+	if level := StepDifficulty(1); level.Name != "expert" {
+		t.Errorf("Expected expert for Elo 1950, got %s", level.Name)
+	}
+}
+
+func TestPlayerName_WithoutDifficulty(t *testing.T) {
+	currentDifficulty = -1
+	LoadedEngineConfig = EngineConfig{Name: "stockfish", Options: map[string]interface{}{"UCI_Elo": float64(1900)}}
+	if got := PlayerName(); got != "stockfish (Elo: 1900)" {
+		t.Errorf("Unexpected player name: %s", got)
+	}
+}
+
+func TestPlayerName_UnlimitedLevel(t *testing.T) {
+	LoadedEngineConfig = EngineConfig{Name: "stockfish", Options: map[string]interface{}{}}
+	defer func() { currentDifficulty = -1 }()
+	for level, want := range map[string]string{
+		"maximum":  "stockfish (Maximum)",
+		"beginner": "stockfish (Beginner)", // Weakened by skill and random moves, not by its Elo
+		"club":     "stockfish (Club, Elo: 1700)",
+	} {
+		if err := SetDifficulty(level); err != nil {
+			t.Fatal(err)
+		}
+		if got := PlayerName(); got != want {
+			t.Errorf("PlayerName() at %s = %s, want %s", level, got, want)
+		}
+	}
+	currentDifficulty = -1
+	LoadedEngineConfig.Options = map[string]interface{}{}
+	if got := PlayerName(); got != "stockfish" {
+		t.Errorf("Expected no Elo without a configured one, got %s", got)
+	}
+}
+
+func TestRandomMove_IsLegal(t *testing.T) {
+	fen := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	move, err := randomMove(fen)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := (chess.UCINotation{}).Decode(chess.StartingPosition(), move); err != nil {
+		t.Errorf("Expected legal move, got %s", move)
+	}
+}
@@ -11,13 +11,18 @@ import (
 )
 
 type EngineConfig struct {
-	Name        string                 `json:"name"`
-	Threads     int                    `json:"threads"`
-	Depth       int                    `json:"depth"`
-	Automove    bool                   `json:"automove"`
-	EngineColor string                 `json:"engineColor"`
-	Path        string                 `json:"path"`
-	Options     map[string]interface{} `json:"options"`
+	Name          string                 `json:"name"`
+	Threads       int                    `json:"threads"`
+	Depth         int                    `json:"depth"`
+	Automove      bool                   `json:"automove"`
+	EngineColor   string                 `json:"engineColor"`
+	Path          string                 `json:"path"`
+	Options       map[string]interface{} `json:"options"`
+	Difficulty    string                 `json:"difficulty"`    // Name of the difficulty level to start with
+	Difficulties  []Difficulty           `json:"difficulties"`  // Optional replacement for DifficultyPresets
+	Books         []string               `json:"books"`         // Polyglot opening books, consulted in order
+	BookDepth     int                    `json:"bookDepth"`     // Number of plies from the start that use the books, 0 means no limit
+	BookSelection string                 `json:"bookSelection"` // "weighted" (default) or "best"
 }
 
 // Engine wraps a Stockfish process.
@@ -34,15 +39,40 @@ var (
 )
 
 func Initialize(engineConfigPath string) error {
-	// Load engine configuration
+	if err := start(engineConfigPath); err != nil {
+		return err
+	}
+	if LoadedEngineConfig.Difficulty != "" {
+		return SetDifficulty(LoadedEngineConfig.Difficulty)
+	}
+	return nil
+}
+
+// InitializeWithDifficulty starts the engine like Initialize, but selects the
+// given difficulty level instead of the configured one. An empty name selects
+// no level, leaving the engine at the strength of its configured options.
+func InitializeWithDifficulty(engineConfigPath, difficulty string) error {
+	if err := start(engineConfigPath); err != nil {
+		return err
+	}
+	if difficulty != "" {
+		return SetDifficulty(difficulty)
+	}
+	return nil
+}
+
+// start loads the engine configuration and launches the engine.
+func start(engineConfigPath string) error {
 	cfg, err := loadEngineConfig(engineConfigPath)
 	if err != nil {
 		return fmt.Errorf("failed to load engine config: %w", err)
 	}
 	LoadedEngineConfig = cfg
+	loadBooks(cfg.Books)
 
-	startStockfishWithOptions(cfg.Path, cfg.Options)
-
+	if err := startStockfishWithOptions(cfg.Path, cfg.Options); err != nil {
+		return fmt.Errorf("failed to start engine %s: %w", cfg.Path, err)
+	}
 	return nil
 }
 
@@ -103,6 +133,19 @@ func startStockfishWithOptions(path string, options map[string]interface{}) erro
 	return nil
 }
 
+// SetOption sets a UCI option in the loaded configuration and, if the engine is
+// running, sends it to the engine.
+func SetOption(name string, value interface{}) {
+	if LoadedEngineConfig.Options == nil {
+		LoadedEngineConfig.Options = map[string]interface{}{}
+	}
+	LoadedEngineConfig.Options[name] = value
+	if loadedEngine == nil {
+		return
+	}
+	SendCommand(fmt.Sprintf("setoption name %s value %v", name, value))
+}
+
 // SendCommand sends a command to Stockfish.
 func SendCommand(cmd string) {
 	loadedEngine.stdin.WriteString(cmd + "\n")
@@ -121,8 +164,27 @@ func readUntil(substr string) {
 
 // GetBestMove returns the best move for a given FEN position.
 func GetBestMove(fen string, depth int) (string, error) {
+	return GetBestMoveWithLimits(fen, depth, 0)
+}
+
+// GetBestMoveWithLimits returns the best move for a given FEN position, searching
+// up to the given depth and move time in milliseconds. A zero limit is ignored.
+func GetBestMoveWithLimits(fen string, depth, moveTime int) (string, error) {
+	if loadedEngine == nil {
+		return "", fmt.Errorf("engine not running")
+	}
+	goCmd := "go"
+	if depth > 0 {
+		goCmd += fmt.Sprintf(" depth %d", depth)
+	}
+	if moveTime > 0 {
+		goCmd += fmt.Sprintf(" movetime %d", moveTime)
+	}
+	if goCmd == "go" {
+		goCmd += fmt.Sprintf(" depth %d", defaultDepth)
+	}
 	SendCommand("position fen " + fen)
-	SendCommand(fmt.Sprintf("go depth %d", depth))
+	SendCommand(goCmd)
 	for loadedEngine.stdout.Scan() {
 		line := loadedEngine.stdout.Text()
 		if strings.HasPrefix(line, "bestmove") {
@@ -138,7 +200,7 @@ func GetBestMove(fen string, depth int) (string, error) {
 
 // Close terminates the Stockfish process.
 func Close() {
-	if loadedEngine.cmd != nil && loadedEngine.cmd.Process != nil {
+	if loadedEngine != nil && loadedEngine.cmd != nil && loadedEngine.cmd.Process != nil {
 		loadedEngine.cmd.Process.Kill()
 	}
 }
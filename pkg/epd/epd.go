@@ -0,0 +1,188 @@
+// Package epd reads Extended Position Description files, as used by engine
+// test suites such as WAC, STS and Bratko-Kopec, and checks engine moves
+// against their bm (best move) and am (avoid move) operations.
+package epd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/corentings/chess"
+)
+
+// Operation is an EPD operation such as bm Nf3 or id "WAC.001".
+type Operation struct {
+	Opcode   string
+	Operands []string
+}
+
+// Position is one line of an EPD file.
+type Position struct {
+	FEN        string      // Full FEN built from the four EPD fields and the hmvc/fmvn operations
+	Operations []Operation // Operations in file order
+	Line       int         // Line number in the file, starting at 1
+}
+
+// Operands returns the operands of the first operation with the given opcode.
+func (p Position) Operands(opcode string) []string {
+	for _, op := range p.Operations {
+		if op.Opcode == opcode {
+			return op.Operands
+		}
+	}
+	return nil
+}
+
+// ID returns the id operation of the position, or its line number if it has none.
+func (p Position) ID() string {
+	if id := p.Operands("id"); len(id) > 0 {
+		return id[0]
+	}
+	return fmt.Sprintf("line %d", p.Line)
+}
+
+// Parse parses one EPD line.
+func Parse(line string) (Position, error) {
+	fields, rest := cutFields(line, 4)
+	if len(fields) < 4 {
+		return Position{}, fmt.Errorf("expected at least 4 fields, got %d", len(fields))
+	}
+	ops, err := parseOperations(rest)
+	if err != nil {
+		return Position{}, err
+	}
+	p := Position{Operations: ops}
+	halfMoves, fullMoves := "0", "1"
+	if hmvc := p.Operands("hmvc"); len(hmvc) > 0 {
+		halfMoves = hmvc[0]
+	}
+	if fmvn := p.Operands("fmvn"); len(fmvn) > 0 {
+		fullMoves = fmvn[0]
+	}
+	p.FEN = strings.Join(append(fields, halfMoves, fullMoves), " ")
+	if _, err := chess.FEN(p.FEN); err != nil {
+		return Position{}, fmt.Errorf("invalid position: %w", err)
+	}
+	return p, nil
+}
+
+// cutFields splits the first n whitespace separated fields from line and
+// returns them together with the rest of the line.
+func cutFields(line string, n int) ([]string, string) {
+	var fields []string
+	rest := strings.TrimSpace(line)
+	for len(fields) < n && rest != "" {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimSpace(rest[end:])
+	}
+	return fields, rest
+}
+
+// parseOperations parses the operations after the four position fields. Each
+// operation is terminated by a semicolon, and operands may be quoted strings.
+func parseOperations(text string) ([]Operation, error) {
+	var ops []Operation
+	var words []string
+	var cur strings.Builder
+	inQuotes, quoted := false, false
+	flushWord := func() {
+		if cur.Len() > 0 || quoted {
+			words = append(words, cur.String())
+			cur.Reset()
+		}
+		quoted = false
+	}
+	for _, c := range text {
+		switch {
+		case inQuotes && c == '"':
+			inQuotes = false
+		case inQuotes:
+			cur.WriteRune(c)
+		case c == '"':
+			inQuotes, quoted = true, true
+		case c == ';':
+			flushWord()
+			if len(words) > 0 {
+				ops = append(ops, Operation{Opcode: words[0], Operands: words[1:]})
+			}
+			words = nil
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			flushWord()
+		default:
+			cur.WriteRune(c)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	flushWord()
+	if len(words) > 0 {
+		return nil, fmt.Errorf("operation %q is not terminated by a semicolon", words[0])
+	}
+	return ops, nil
+}
+
+// Read reads all positions from r. Empty lines and lines starting with # are skipped.
+func Read(r io.Reader) ([]Position, error) {
+	var positions []Position
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		p.Line = lineNumber
+		positions = append(positions, p)
+	}
+	return positions, scanner.Err()
+}
+
+// ToUCI converts moves in SAN, as used by bm and am, to UCI notation.
+func (p Position) ToUCI(sans []string) ([]string, error) {
+	opt, err := chess.FEN(p.FEN)
+	if err != nil {
+		return nil, err
+	}
+	pos := chess.NewGame(opt).Position()
+	ucis := make([]string, len(sans))
+	for i, san := range sans {
+		move, err := chess.AlgebraicNotation{}.Decode(pos, strings.TrimRight(san, "+#!?"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid move %q: %w", san, err)
+		}
+		ucis[i] = chess.UCINotation{}.Encode(pos, move)
+	}
+	return ucis, nil
+}
+
+// ToSAN converts a move in UCI notation to SAN, or returns it unchanged if it
+// is not legal in the position.
+func (p Position) ToSAN(uci string) string {
+	opt, err := chess.FEN(p.FEN)
+	if err != nil {
+		return uci
+	}
+	pos := chess.NewGame(opt).Position()
+	move, err := chess.UCINotation{}.Decode(pos, uci)
+	if err != nil {
+		return uci
+	}
+	for _, legal := range pos.ValidMoves() {
+		if legal.S1() == move.S1() && legal.S2() == move.S2() && legal.Promo() == move.Promo() {
+			return chess.AlgebraicNotation{}.Encode(pos, legal)
+		}
+	}
+	return uci
+}
@@ -0,0 +1,63 @@
+package epd
+
+import (
+	"strings"
+	"testing"
+)
+
+const suite = `# Win at Chess
+2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";
+r1b1kb1r/3q1ppp/pBp1pn2/8/Np3P2/5B2/PPP3PP/R2Q1RK1 w kq - bm Bxc6; id "WAC.003";
+5rk1/1ppb3p/p1pb4/6q1/3P1p1r/2P1R2P/PP1BQ1P1/5RKN w - - am Ref3; id "test: avoid"; c0 "semi;colon";
+`
+
+func TestRead_Operations(t *testing.T) {
+	positions, err := Read(strings.NewReader(suite))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(positions) != 3 {
+		t.Fatalf("Expected 3 positions, got %d", len(positions))
+	}
+	if positions[0].ID() != "WAC.001" || positions[0].Line != 2 {
+		t.Errorf("Unexpected first position: %+v", positions[0])
+	}
+	if got := positions[0].FEN; got != "2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1" {
+		t.Errorf("Unexpected FEN %q", got)
+	}
+	if c0 := positions[2].Operands("c0"); len(c0) != 1 || c0[0] != "semi;colon" {
+		t.Errorf("Expected quoted operand with semicolon, got %v", c0)
+	}
+	if _, err := Parse("8/8/8/8/8/8/8/8 w - - bm Kd2"); err == nil {
+		t.Error("Expected error for unterminated operation")
+	}
+}
+
+func TestRun_ChecksBestAndAvoidMoves(t *testing.T) {
+	positions, err := Read(strings.NewReader(suite))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	answers := map[string]string{
+		positions[0].FEN: "g3g6", // Qg6, solved
+		positions[1].FEN: "d1d7", // Qxd7 instead of Bxc6, failed
+		positions[2].FEN: "e3f3", // Rf3, avoided move played
+	}
+	results := Run(positions, func(fen string) (string, error) { return answers[fen], nil }, nil)
+	if !results[0].Solved || results[0].Move != "Qg6" {
+		t.Errorf("Expected WAC.001 to be solved with Qg6, got %+v", results[0])
+	}
+	if results[1].Solved {
+		t.Errorf("Expected WAC.003 to fail, got %+v", results[1])
+	}
+	if results[2].Solved {
+		t.Errorf("Expected avoid move to fail, got %+v", results[2])
+	}
+	if results[2].Error != "" {
+		t.Errorf("Expected no error, got %s", results[2].Error)
+	}
+	s := Summarize(results)
+	if s.Total != 3 || s.Solved != 1 || s.Failed != 2 {
+		t.Errorf("Unexpected summary %+v", s)
+	}
+}
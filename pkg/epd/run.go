@@ -0,0 +1,159 @@
+package epd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Result is the outcome of one position of a test suite.
+type Result struct {
+	ID         string   `json:"id"`
+	FEN        string   `json:"fen"`
+	Move       string   `json:"move"`                 // Engine move in SAN
+	BestMoves  []string `json:"bestMoves,omitempty"`  // bm operands
+	AvoidMoves []string `json:"avoidMoves,omitempty"` // am operands
+	Solved     bool     `json:"solved"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Summary holds the totals of a test suite run.
+type Summary struct {
+	Total  int `json:"total"`
+	Solved int `json:"solved"`
+	Failed int `json:"failed"`
+	Errors int `json:"errors"` // Positions that could not be checked
+}
+
+// SearchFunc returns the move an engine plays in the position, in UCI notation.
+type SearchFunc func(fen string) (string, error)
+
+// Check reports whether the move (UCI) solves the position: it must be one of
+// the bm moves, if any, and none of the am moves.
+func (p Position) Check(move string) (bool, error) {
+	best, err := p.ToUCI(p.Operands("bm"))
+	if err != nil {
+		return false, err
+	}
+	avoid, err := p.ToUCI(p.Operands("am"))
+	if err != nil {
+		return false, err
+	}
+	if len(best) == 0 && len(avoid) == 0 {
+		return false, fmt.Errorf("position has no bm or am operation")
+	}
+	if len(best) > 0 && !slices.Contains(best, move) {
+		return false, nil
+	}
+	return !slices.Contains(avoid, move), nil
+}
+
+// Run searches every position and checks the moves. The progress function, if
+// not nil, is called after each position.
+func Run(positions []Position, search SearchFunc, progress func(Result)) []Result {
+	results := make([]Result, 0, len(positions))
+	for _, p := range positions {
+		r := Result{
+			ID:         p.ID(),
+			FEN:        p.FEN,
+			BestMoves:  p.Operands("bm"),
+			AvoidMoves: p.Operands("am"),
+		}
+		move, err := search(p.FEN)
+		if err == nil {
+			r.Move = p.ToSAN(move)
+			r.Solved, err = p.Check(move)
+		}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		results = append(results, r)
+		if progress != nil {
+			progress(r)
+		}
+	}
+	return results
+}
+
+// Summarize counts the solved and failed positions.
+func Summarize(results []Result) Summary {
+	s := Summary{Total: len(results)}
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			s.Errors++
+		case r.Solved:
+			s.Solved++
+		default:
+			s.Failed++
+		}
+	}
+	return s
+}
+
+// status returns the result as a single word.
+func (r Result) status() string {
+	switch {
+	case r.Error != "":
+		return "error"
+	case r.Solved:
+		return "solved"
+	}
+	return "failed"
+}
+
+// expected describes the bm and am operands, e.g. "bm Qg6; am Qxb7".
+func (r Result) expected() string {
+	var parts []string
+	if len(r.BestMoves) > 0 {
+		parts = append(parts, "bm "+strings.Join(r.BestMoves, " "))
+	}
+	if len(r.AvoidMoves) > 0 {
+		parts = append(parts, "am "+strings.Join(r.AvoidMoves, " "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// WriteText writes one line per position followed by the totals.
+func WriteText(w io.Writer, results []Result) error {
+	for _, r := range results {
+		line := fmt.Sprintf("%-7s %-20s %-8s (%s)", r.status(), r.ID, r.Move, r.expected())
+		if r.Error != "" {
+			line += ": " + r.Error
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	s := Summarize(results)
+	percent := 0.0
+	if s.Total > 0 {
+		percent = float64(s.Solved) * 100 / float64(s.Total)
+	}
+	_, err := fmt.Fprintf(w, "\nSolved %d of %d (%.1f%%), failed %d, errors %d\n", s.Solved, s.Total, percent, s.Failed, s.Errors)
+	return err
+}
+
+// WriteCSV writes one row per position with a header row.
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "status", "move", "bm", "am", "fen", "error"})
+	for _, r := range results {
+		cw.Write([]string{r.ID, r.status(), r.Move, strings.Join(r.BestMoves, " "), strings.Join(r.AvoidMoves, " "), r.FEN, r.Error})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the results and totals as one JSON object.
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Summary   Summary  `json:"summary"`
+		Positions []Result `json:"positions"`
+	}{Summarize(results), results})
+}
@@ -0,0 +1,193 @@
+// Package gamedb is a local database of PGN games. Games are stored in a JSON
+// file together with the Polyglot keys of all positions they reach, and are
+// indexed by player, date, result, ECO code and position when the database is
+// opened.
+package gamedb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/book"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/corentings/chess"
+)
+
+// Game is a game stored in the database.
+type Game struct {
+	ID        int       `json:"id"`        // Number of the game, starting at 1
+	Source    string    `json:"source"`    // File the game was imported from
+	Raw       string    `json:"pgn"`       // The complete PGN text
+	Tags      []pgn.Tag `json:"tags"`      // Tag pairs in file order
+	Plies     int       `json:"plies"`     // Number of main line half moves
+	Positions []uint64  `json:"positions"` // Polyglot keys of all positions, start included
+}
+
+// Tag returns the value of the named tag, or an empty string if it is missing.
+func (g *Game) Tag(name string) string {
+	return pgn.TagValue(g.Tags, name)
+}
+
+// Info returns the game as listed in a PGN file. Index is the ID minus one.
+func (g *Game) Info() pgn.GameInfo {
+	return pgn.GameInfo{Index: g.ID - 1, Tags: g.Tags, Moves: g.Plies, Raw: g.Raw}
+}
+
+// DB is a game database loaded into memory.
+type DB struct {
+	path  string
+	games []*Game
+	seen  map[string]bool // Checksums of the stored games, to skip duplicates
+
+	byWhite    map[string][]int // Lower case player name to game IDs
+	byBlack    map[string][]int
+	byDate     map[string][]int
+	byResult   map[string][]int
+	byECO      map[string][]int
+	byPosition map[uint64][]int // Polyglot key to IDs of the games reaching it
+}
+
+// Open loads the database stored at path. A missing file is an empty database.
+func Open(path string) (*DB, error) {
+	db := &DB{
+		path:       path,
+		seen:       map[string]bool{},
+		byWhite:    map[string][]int{},
+		byBlack:    map[string][]int{},
+		byDate:     map[string][]int{},
+		byResult:   map[string][]int{},
+		byECO:      map[string][]int{},
+		byPosition: map[uint64][]int{},
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var games []*Game
+	if err := json.Unmarshal(data, &games); err != nil {
+		return nil, err
+	}
+	for _, g := range games {
+		db.add(g)
+	}
+	return db, nil
+}
+
+// Save writes the database back to its file.
+func (db *DB) Save() error {
+	if err := os.MkdirAll(filepath.Dir(db.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(db.games)
+	if err != nil {
+		return err
+	}
+	// Write a temporary file first so an interrupted save keeps the old database
+	tmp := db.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, db.path)
+}
+
+// Len returns the number of stored games.
+func (db *DB) Len() int {
+	return len(db.games)
+}
+
+// Game returns the game with the given ID.
+func (db *DB) Game(id int) (*Game, bool) {
+	if id < 1 || id > len(db.games) {
+		return nil, false
+	}
+	return db.games[id-1], true
+}
+
+// Games returns all stored games in import order.
+func (db *DB) Games() []*Game {
+	return db.games
+}
+
+// Import adds all games of a PGN file and returns the number of added games.
+// Games already in the database are skipped.
+func (db *DB) Import(r io.Reader, source string) (added int, err error) {
+	raws, err := pgn.Split(r)
+	if err != nil {
+		return 0, err
+	}
+	for _, raw := range raws {
+		if db.seen[checksum(raw)] {
+			continue
+		}
+		g := &Game{Source: source, Raw: raw, Tags: pgn.ParseTags(raw)}
+		g.Plies, g.Positions = replay(raw)
+		db.add(g)
+		added++
+	}
+	return added, nil
+}
+
+// ImportPath imports a PGN file, or all .pgn files below a directory.
+func (db *DB) ImportPath(path string) (added int, err error) {
+	err = pgn.WalkFiles(path, func(p string, r io.Reader) error {
+		n, err := db.Import(r, p)
+		added += n
+		return err
+	})
+	return added, err
+}
+
+// add stores a game and adds it to the indexes.
+func (db *DB) add(g *Game) {
+	g.ID = len(db.games) + 1
+	db.games = append(db.games, g)
+	db.seen[checksum(g.Raw)] = true
+	white, black, eco := strings.ToLower(g.Tag("White")), strings.ToLower(g.Tag("Black")), strings.ToUpper(g.Tag("ECO"))
+	db.byWhite[white] = append(db.byWhite[white], g.ID)
+	db.byBlack[black] = append(db.byBlack[black], g.ID)
+	db.byDate[g.Tag("Date")] = append(db.byDate[g.Tag("Date")], g.ID)
+	db.byResult[g.Tag("Result")] = append(db.byResult[g.Tag("Result")], g.ID)
+	db.byECO[eco] = append(db.byECO[eco], g.ID)
+	for _, key := range g.Positions {
+		// Repeated positions are listed once per game
+		if ids := db.byPosition[key]; len(ids) > 0 && ids[len(ids)-1] == g.ID {
+			continue
+		}
+		db.byPosition[key] = append(db.byPosition[key], g.ID)
+	}
+}
+
+// checksum identifies a game independent of its line breaks and spacing.
+func checksum(raw string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(raw), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// replay returns the number of main line moves and the Polyglot keys of all
+// positions of a game. Games the chess library cannot replay, such as Chess960
+// games, have no positions and only count their moves.
+func replay(raw string) (plies int, positions []uint64) {
+	plies = len(pgn.MainlineSAN(pgn.Movetext(raw)))
+	gameFunc, err := chess.PGN(strings.NewReader(raw))
+	if err != nil {
+		return plies, nil
+	}
+	for _, pos := range chess.NewGame(gameFunc).Positions() {
+		key, err := book.Hash(pos.String())
+		if err != nil {
+			return plies, nil
+		}
+		positions = append(positions, key)
+	}
+	return plies, positions
+}
@@ -0,0 +1,124 @@
+package gamedb
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testGames = `[Event "Club Championship"]
+[White "Carlsen, Magnus"]
+[Black "Nakamura, Hikaru"]
+[Date "2024.05.01"]
+[Result "1-0"]
+[ECO "E20"]
+
+1. d4 Nf6 2. c4 e6 3. Nc3 Bb4 1-0
+
+[Event "Blitz"]
+[White "Nakamura, Hikaru"]
+[Black "Carlsen, Magnus"]
+[Date "2023.11.20"]
+[Result "1/2-1/2"]
+[ECO "A13"]
+
+1. c4 e6 2. Nc3 Nf6 3. d4 Bb4 1/2-1/2
+
+[Event "Blitz"]
+[White "Doe, Jane"]
+[Black "Roe, Richard"]
+[Date "2024.01.15"]
+[Result "0-1"]
+[ECO "C60"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 0-1
+`
+
+func ids(games []*Game) []int {
+	var ids []int
+	for _, g := range games {
+		ids = append(ids, g.ID)
+	}
+	return ids
+}
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "games.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.Import(strings.NewReader(testGames), "test.pgn"); err != nil || n != 3 {
+		t.Fatalf("Expected 3 imported games, got %d (%v)", n, err)
+	}
+	return db
+}
+
+func TestSearch(t *testing.T) {
+	db := openTestDB(t)
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", []int{1, 2, 3}},
+		{"carlsen", []int{1, 2}},
+		{"white:carlsen", []int{1}},
+		{"carlsen nakamura result:1/2-1/2", []int{2}},
+		{"date:2024", []int{1, 3}},
+		{"eco:e", []int{1}},
+		{"event:blitz", []int{2, 3}},
+		{"roe", []int{3}},
+		{"kasparov", nil},
+		// Both move orders reach the Nimzo-Indian
+		{`fen:"rnbqk2r/pppp1ppp/4pn2/8/1bPP4/2N5/PP2PPPP/R1BQKBNR w KQkq - 2 4"`, []int{1, 2}},
+		{`fen:"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1" date:2024`, []int{3}},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		games, err := db.Search(q)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if got := ids(games); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	for _, query := range []string{`fen:"8/8/8`, `fen:nonsense`} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("Expected an error for %q", query)
+		}
+	}
+}
+
+func TestSaveAndReopen(t *testing.T) {
+	db := openTestDB(t)
+	// Importing the same games again adds nothing
+	if n, _ := db.Import(strings.NewReader(testGames), "again.pgn"); n != 0 {
+		t.Errorf("Expected duplicates to be skipped, got %d new games", n)
+	}
+	if err := db.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(db.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != 3 {
+		t.Fatalf("Expected 3 games after reopening, got %d", reopened.Len())
+	}
+	g, ok := reopened.Game(3)
+	if !ok || g.Tag("White") != "Doe, Jane" || g.Plies != 5 || len(g.Positions) != 6 || g.Source != "test.pgn" {
+		t.Errorf("Unexpected game after reopening: %+v", g)
+	}
+	q, _ := ParseQuery("eco:C6")
+	if games, _ := reopened.Search(q); !reflect.DeepEqual(ids(games), []int{3}) {
+		t.Errorf("Expected the index to be rebuilt, got %v", ids(games))
+	}
+}
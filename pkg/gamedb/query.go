@@ -0,0 +1,216 @@
+package gamedb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/book"
+)
+
+// Query selects games. Empty fields match every game, and a game must match
+// all given fields.
+type Query struct {
+	Players []string          // Substrings of the White or Black name, one per player
+	White   string            // Substring of the White name
+	Black   string            // Substring of the Black name
+	Date    string            // Date prefix, e.g. "2024" or "2024.05"
+	Result  string            // "1-0", "0-1", "1/2-1/2" or "*"
+	ECO     string            // ECO code prefix, e.g. "B9"
+	FEN     string            // Position the game must reach
+	Tags    map[string]string // Other tags, as case-insensitive substrings
+}
+
+// ParseQuery reads a query of space separated terms. Plain words match either
+// player, and name:value terms match one field: white, black, player, date,
+// result, eco, fen or any other tag name. Values containing spaces are quoted,
+// e.g. `carlsen eco:B9 fen:"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"`.
+func ParseQuery(s string) (Query, error) {
+	var q Query
+	terms, err := splitTerms(s)
+	if err != nil {
+		return q, err
+	}
+	for _, term := range terms {
+		name, value, ok := strings.Cut(term, ":")
+		if !ok || name == "" {
+			q.Players = append(q.Players, term)
+			continue
+		}
+		switch strings.ToLower(name) {
+		case "player":
+			q.Players = append(q.Players, value)
+		case "white":
+			q.White = value
+		case "black":
+			q.Black = value
+		case "date":
+			q.Date = value
+		case "result":
+			q.Result = value
+		case "eco":
+			q.ECO = value
+		case "fen":
+			if _, err := book.Hash(value); err != nil {
+				return q, err
+			}
+			q.FEN = value
+		default:
+			if q.Tags == nil {
+				q.Tags = map[string]string{}
+			}
+			q.Tags[strings.ToLower(name)] = value
+		}
+	}
+	return q, nil
+}
+
+// splitTerms splits s at spaces outside of double quotes and removes the quotes.
+func splitTerms(s string) ([]string, error) {
+	var terms []string
+	var cur strings.Builder
+	quoted, inTerm := false, false
+	for _, c := range s {
+		switch {
+		case c == '"':
+			quoted, inTerm = !quoted, true
+		case !quoted && (c == ' ' || c == '\t' || c == '\n'):
+			if inTerm {
+				terms = append(terms, cur.String())
+				cur.Reset()
+				inTerm = false
+			}
+		default:
+			cur.WriteRune(c)
+			inTerm = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inTerm {
+		terms = append(terms, cur.String())
+	}
+	return terms, nil
+}
+
+// Search returns the games matching the query in import order.
+func (db *DB) Search(q Query) ([]*Game, error) {
+	var ids []int
+	all := true // No indexed field restricted the games yet
+	restrict := func(matches []int) {
+		if all {
+			ids, all = matches, false
+		} else {
+			ids = intersect(ids, matches)
+		}
+	}
+	contains := func(sub string) func(string) bool {
+		sub = strings.ToLower(sub)
+		return func(name string) bool { return strings.Contains(name, sub) }
+	}
+
+	for _, p := range q.Players {
+		restrict(union(collect(db.byWhite, contains(p)), collect(db.byBlack, contains(p))))
+	}
+	if q.White != "" {
+		restrict(collect(db.byWhite, contains(q.White)))
+	}
+	if q.Black != "" {
+		restrict(collect(db.byBlack, contains(q.Black)))
+	}
+	if q.Date != "" {
+		restrict(collect(db.byDate, func(date string) bool { return strings.HasPrefix(date, q.Date) }))
+	}
+	if q.Result != "" {
+		restrict(db.byResult[q.Result])
+	}
+	if q.ECO != "" {
+		eco := strings.ToUpper(q.ECO)
+		restrict(collect(db.byECO, func(code string) bool { return strings.HasPrefix(code, eco) }))
+	}
+	if q.FEN != "" {
+		key, err := book.Hash(q.FEN)
+		if err != nil {
+			return nil, err
+		}
+		restrict(db.byPosition[key])
+	}
+
+	var candidates []*Game
+	if all {
+		candidates = db.games
+	} else {
+		for _, id := range ids {
+			candidates = append(candidates, db.games[id-1])
+		}
+	}
+	// Tags without an index are compared game by game
+	var games []*Game
+	for _, g := range candidates {
+		if matchesTags(g, q.Tags) {
+			games = append(games, g)
+		}
+	}
+	return games, nil
+}
+
+func matchesTags(g *Game, tags map[string]string) bool {
+	for name, value := range tags {
+		found := false
+		for _, t := range g.Tags {
+			if strings.EqualFold(t.Name, name) && strings.Contains(strings.ToLower(t.Value), strings.ToLower(value)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// collect returns the sorted IDs of all index entries whose key matches.
+func collect(index map[string][]int, match func(string) bool) []int {
+	var ids []int
+	for key, keyIDs := range index {
+		if match(key) {
+			ids = union(ids, keyIDs)
+		}
+	}
+	return ids
+}
+
+// union merges two sorted ID lists.
+func union(a, b []int) []int {
+	merged := make([]int, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0] < b[0]):
+			merged, a = append(merged, a[0]), a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	return merged
+}
+
+// intersect returns the IDs found in both sorted lists.
+func intersect(a, b []int) []int {
+	var common []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			common = append(common, a[i])
+			i++
+			j++
+		}
+	}
+	return common
+}
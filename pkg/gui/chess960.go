@@ -0,0 +1,220 @@
+package gui
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/RubikNube/TerminalChess/pkg/history"
+	"github.com/corentings/chess"
+)
+
+// Chess960 reports whether the current game follows Chess960 rules.
+// It is set by NewChess960Board and cleared by UseStandardCastling.
+var Chess960 bool = false
+
+// castlingFiles holds the start columns of the king and the castling rooks.
+var castlingFiles = struct {
+	king, queenRook, kingRook int
+}{4, 0, 7}
+
+// StandardPosition is the Chess960 start position number of the standard setup.
+const StandardPosition = 518
+
+// knightPlacements lists the knight columns among the five remaining squares
+// for the Scharnagl numbering of the Chess960 start positions.
+var knightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960BackRank returns the back rank piece order for the start position
+// number sp (0-959), following the Scharnagl numbering where 518 is the
+// standard setup.
+func Chess960BackRank(sp int) ([8]PieceType, error) {
+	var rank [8]PieceType
+	if sp < 0 || sp > 959 {
+		return rank, fmt.Errorf("start position must be between 0 and 959, got %d", sp)
+	}
+	for i := range rank {
+		rank[i] = Empty
+	}
+	n := sp
+	rank[2*(n%4)+1] = Bishop // light-squared bishop on b, d, f or h
+	n /= 4
+	rank[2*(n%4)] = Bishop // dark-squared bishop on a, c, e or g
+	n /= 4
+	placeOnFree(&rank, n%6, Queen)
+	n /= 6
+	knights := knightPlacements[n]
+	// Place the second knight first so the first index is not shifted
+	placeOnFree(&rank, knights[1], Knight)
+	placeOnFree(&rank, knights[0], Knight)
+	// The remaining three squares are rook, king, rook
+	placeOnFree(&rank, 0, Rook)
+	placeOnFree(&rank, 0, King)
+	placeOnFree(&rank, 0, Rook)
+	return rank, nil
+}
+
+// placeOnFree puts the piece on the n-th empty square of the rank.
+func placeOnFree(rank *[8]PieceType, n int, piece PieceType) {
+	for i := range rank {
+		if rank[i] != Empty {
+			continue
+		}
+		if n == 0 {
+			rank[i] = piece
+			return
+		}
+		n--
+	}
+}
+
+// RandomChess960Position returns a random Chess960 start position number.
+func RandomChess960Position() int {
+	return rand.Intn(960)
+}
+
+// NewChess960Board initializes a board with the Chess960 start position sp and
+// switches castling to Chess960 rules.
+func NewChess960Board(sp int) (ChessBoard, error) {
+	backRank, err := Chess960BackRank(sp)
+	if err != nil {
+		return ChessBoard{}, err
+	}
+	board := NewChessBoard()
+	for col, typ := range backRank {
+		board[0][col] = Piece{Color: Black, Type: typ}
+		board[7][col] = Piece{Color: White, Type: typ}
+	}
+	useChess960Castling(backRank)
+	return board, nil
+}
+
+// RestoreChess960 switches castling to Chess960 rules for a game that started
+// from fen, e.g. when resuming a saved game.
+func RestoreChess960(fen string) error {
+	start := NewChessBoardFromFEN(fen)
+	var backRank [8]PieceType
+	for col := range backRank {
+		if start[7][col].Color != White {
+			return fmt.Errorf("invalid Chess960 start position %q", fen)
+		}
+		backRank[col] = start[7][col].Type
+	}
+	useChess960Castling(backRank)
+	return nil
+}
+
+// useChess960Castling takes the castling files from the back rank of the start position.
+func useChess960Castling(backRank [8]PieceType) {
+	rooks := 0
+	for col, typ := range backRank {
+		switch typ {
+		case King:
+			castlingFiles.king = col
+		case Rook:
+			// The king always stands between the rooks, so the first rook is the queenside one
+			if rooks == 0 {
+				castlingFiles.queenRook = col
+			} else {
+				castlingFiles.kingRook = col
+			}
+			rooks++
+		}
+	}
+	Chess960 = true
+}
+
+// UseStandardCastling switches castling back to the standard rules.
+func UseStandardCastling() {
+	Chess960 = false
+	castlingFiles.king = 4
+	castlingFiles.queenRook = 0
+	castlingFiles.kingRook = 7
+}
+
+// Chess960CastlingMove returns the king move that castles king side or queen
+// side in a Chess960 game: the king moves from (row, fromCol) onto its castling
+// rook at (row, toCol).
+func Chess960CastlingMove(turn Color, kingside bool) (row, fromCol, toCol int) {
+	row = 7
+	if turn == Black {
+		row = 0
+	}
+	toCol = castlingFiles.queenRook
+	if kingside {
+		toCol = castlingFiles.kingRook
+	}
+	return row, castlingFiles.king, toCol
+}
+
+// tryChess960Castle performs a Chess960 castling move if the king move from
+// (row, fromCol) to (row, toCol) is one. The king either moves onto its own
+// castling rook (UCI Chess960 encoding) or to the g or c file. handled is false
+// if the move is not a castling attempt.
+func (b *ChessBoard) tryChess960Castle(fromRow, fromCol, toRow, toCol int, turn Color) (ok, handled bool) {
+	backRow := 7
+	if turn == Black {
+		backRow = 0
+	}
+	king := b[fromRow][fromCol]
+	if king.Type != King || king.Color != turn || fromRow != backRow || toRow != backRow || fromCol != castlingFiles.king {
+		return false, false
+	}
+	target := b[toRow][toCol]
+	var rookCol, kingTo, rookTo int
+	switch {
+	case target.Type == Rook && target.Color == turn && toCol == castlingFiles.kingRook:
+		rookCol, kingTo, rookTo = castlingFiles.kingRook, 6, 5
+	case target.Type == Rook && target.Color == turn && toCol == castlingFiles.queenRook:
+		rookCol, kingTo, rookTo = castlingFiles.queenRook, 2, 3
+	case toCol == 6 && abs(toCol-fromCol) >= 2:
+		rookCol, kingTo, rookTo = castlingFiles.kingRook, 6, 5
+	case toCol == 2 && abs(toCol-fromCol) >= 2:
+		rookCol, kingTo, rookTo = castlingFiles.queenRook, 2, 3
+	default:
+		return false, false
+	}
+	rook := b[backRow][rookCol]
+	if rook.Type != Rook || rook.Color != turn {
+		return false, true
+	}
+
+	// Every square the king and rook travel over must be empty, apart from the two castling pieces
+	for _, span := range [][2]int{{fromCol, kingTo}, {rookCol, rookTo}} {
+		lo, hi := span[0], span[1]
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		for col := lo; col <= hi; col++ {
+			if col != fromCol && col != rookCol && b[backRow][col].Type != Empty {
+				return false, true
+			}
+		}
+	}
+
+	// The king may not be in check on its start square, on its way or on its target square
+	lo, hi := fromCol, kingTo
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	for col := lo; col <= hi; col++ {
+		probe := *b
+		probe[backRow][fromCol] = Piece{Color: Undefined, Type: Empty}
+		probe[backRow][col] = king
+		fenFunc, err := chess.FEN(history.WithoutCastling(probe.ToFEN(turn)))
+		if err != nil || history.IsInCheck(chess.NewGame(fenFunc)) {
+			return false, true
+		}
+	}
+
+	b[backRow][fromCol] = Piece{Color: Undefined, Type: Empty}
+	b[backRow][rookCol] = Piece{Color: Undefined, Type: Empty}
+	b[backRow][kingTo] = king
+	b[backRow][rookTo] = rook
+	// Record the move in the UCI Chess960 encoding: king takes own rook
+	history.AddMove(fmt.Sprintf("%c%d%c%d", 'a'+fromCol, 8-backRow, 'a'+rookCol, 8-backRow))
+	setEnPassantSquare(king, fromRow, fromCol, toRow, toCol)
+	return true, true
+}
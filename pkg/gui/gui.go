@@ -4,7 +4,10 @@ package gui
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/RubikNube/TerminalChess/pkg/history"
 	"github.com/corentings/chess"
@@ -62,8 +65,11 @@ var BoardFlipped bool = false
 var enPassantRow int = -1
 var enPassantCol int = -1
 
-// NewChessBoard initializes a chess board with the standard starting position.
+// NewChessBoard initializes a chess board with the standard starting position,
+// switches castling back to the standard rules and clears the en passant square.
 func NewChessBoard() ChessBoard {
+	UseStandardCastling()
+	enPassantRow, enPassantCol = -1, -1
 	board := ChessBoard{}
 	// Initialize pawns
 	for i := 0; i < 8; i++ {
@@ -151,14 +157,30 @@ func NewChessBoardFromFEN(fen string) ChessBoard {
 
 // MovePiece moves a piece from (fromRow, fromCol) to (toRow, toCol) if the move is legal.
 // Now supports castling and en passant by allowing king, rook, and pawn moves as per chess rules.
+// Pawns reaching the last rank are promoted to a queen.
 func (b *ChessBoard) MovePiece(fromRow, fromCol, toRow, toCol int, turn Color) bool {
+	return b.MovePiecePromoting(fromRow, fromCol, toRow, toCol, turn, Queen)
+}
+
+// MovePiecePromoting is MovePiece with the piece a pawn reaching the last rank
+// is promoted to.
+func (b *ChessBoard) MovePiecePromoting(fromRow, fromCol, toRow, toCol int, turn Color, promotion PieceType) bool {
 	// Bounds check
 	if fromRow < 0 || fromRow > 7 || fromCol < 0 || fromCol > 7 ||
 		toRow < 0 || toRow > 7 || toCol < 0 || toCol > 7 {
 		return false
 	}
+	// Chess960 castling is not supported by the chess library, handle it first
+	if Chess960 {
+		if ok, handled := b.tryChess960Castle(fromRow, fromCol, toRow, toCol, turn); handled {
+			return ok
+		}
+	}
 	// Export current board to FEN, with correct turn
 	fen := b.ToFEN(turn)
+	if Chess960 {
+		fen = history.WithoutCastling(fen)
+	}
 	chessFen, err := chess.FEN(fen)
 	if err != nil {
 		return false
@@ -166,17 +188,26 @@ func (b *ChessBoard) MovePiece(fromRow, fromCol, toRow, toCol int, turn Color) b
 	game := chess.NewGame(chessFen)
 	moveStr := fmt.Sprintf("%c%d%c%d", 'a'+fromCol, 8-fromRow, 'a'+toCol, 8-toRow)
 
-	// Handle pawn promotion (promote to queen by default if moving to last rank)
+	// Handle pawn promotion
 	piece := b[fromRow][fromCol]
 	if piece.Type == Pawn && (toRow == 0 || toRow == 7) {
-		moveStr += "q"
+		switch promotion {
+		case Rook:
+			moveStr += "r"
+		case Bishop:
+			moveStr += "b"
+		case Knight:
+			moveStr += "n"
+		default:
+			moveStr += "q"
+		}
 	}
 
 	// Try normal move
 	move, err := chess.UCINotation{}.Decode(game.Position(), moveStr)
 	if err != nil {
 		// Try castling if king moves two squares horizontally
-		if piece.Type == King && fromRow == toRow && abs(fromCol-toCol) == 2 {
+		if !Chess960 && piece.Type == King && fromRow == toRow && abs(fromCol-toCol) == 2 {
 			var castleMove *chess.Move
 			if toCol == 6 { // kingside
 				castleMove, _ = chess.UCINotation{}.Decode(game.Position(), "e1g1")
@@ -208,8 +239,6 @@ func (b *ChessBoard) MovePiece(fromRow, fromCol, toRow, toCol int, turn Color) b
 				} else {
 					b[toRow-1][toCol] = Piece{Type: Empty, Color: Undefined}
 				}
-				moveStr := move.String()
-				moveStr += " e.p."
 				history.AddMove(moveStr)
 				setEnPassantSquare(piece, fromRow, fromCol, toRow, toCol)
 				return true
@@ -252,6 +281,20 @@ func setEnPassantSquare(piece Piece, fromRow, fromCol, toRow, toCol int) {
 	}
 }
 
+// SetEnPassantSquareFromFEN sets the en passant square from the fourth field
+// of a FEN string, and clears it if the FEN names none.
+func SetEnPassantSquareFromFEN(fen string) {
+	enPassantRow, enPassantCol = -1, -1
+	fields := strings.Fields(fen)
+	if len(fields) < 4 || len(fields[3]) != 2 {
+		return
+	}
+	col, row := int(fields[3][0]-'a'), 8-int(fields[3][1]-'0')
+	if col >= 0 && col < 8 && row >= 0 && row < 8 {
+		enPassantRow, enPassantCol = row, col
+	}
+}
+
 func GetEnPassantSquare() (int, int) {
 	// Return the current en passant square
 	return enPassantRow, enPassantCol
@@ -320,11 +363,10 @@ func (b ChessBoard) RenderToView(v *gocui.View, cursorRow, cursorCol int, select
 
 func (b ChessBoard) RenderToViewFlipped(v *gocui.View, cursorRow, cursorCol int, selected bool, selectedRow, selectedCol int, flipped bool) {
 	v.Clear()
-	artHeight := 7
-	artWidth := 7
+	mode := BoardRenderMode
+	squareWidth, artHeight := mode.squareSize()
 	// Top column labels, aligned with board
-	squareWidth := artWidth*2 + 2 // doubled chars + 2 spaces padding
-	fmt.Fprint(v, "  ")
+	fmt.Fprint(v, strings.Repeat(" ", labelWidth))
 	for col := 0; col < 8; col++ {
 		var labelCol int
 		if flipped {
@@ -333,10 +375,10 @@ func (b ChessBoard) RenderToViewFlipped(v *gocui.View, cursorRow, cursorCol int,
 			labelCol = col
 		}
 		label := fmt.Sprintf("%c", 'a'+labelCol)
-		pad := (squareWidth - len(label)) / 2
+		pad := squareWidth / 2
 		fmt.Fprint(v, strings.Repeat(" ", pad))
 		fmt.Fprint(v, label)
-		fmt.Fprint(v, strings.Repeat(" ", squareWidth-pad-len(label)-2))
+		fmt.Fprint(v, strings.Repeat(" ", squareWidth-pad-len(label)))
 	}
 	fmt.Fprintln(v)
 	for i := 0; i < 8; i++ {
@@ -362,40 +404,21 @@ func (b ChessBoard) RenderToViewFlipped(v *gocui.View, cursorRow, cursorCol int,
 					col = j
 				}
 				piece := b[row][col]
-				art := asciiPieces[piece.Type][piece.Color]
-				cell := art[line]
-				var fgColor, bgColor string
+				cell := mode.squareLines(piece)[line]
 				reset := "\033[0m"
 
 				// Determine piece color
+				var fgColor string
 				switch piece.Color {
 				case Black:
-					fgColor = "\033[31m"
+					fgColor = BoardPalette.BlackPiece
 				case White:
-					fgColor = "\033[34m"
-				default:
-					fgColor = "\033[0m"
+					fgColor = BoardPalette.WhitePiece
 				}
-
-				// Determine square color
-				if (row+col)%2 == 0 {
-					bgColor = "\033[47m"
-				} else {
-					bgColor = "\033[40m"
-				}
-				// Cursor highlight: yellow background only (no underline)
-				cursorAttr := ""
-				if row == cursorRow && col == cursorCol {
-					cursorAttr = "\033[43m"
-				}
-
-				// Selected piece highlight: reverse video
-				if selected && row == selectedRow && col == selectedCol {
-					cursorAttr += "\033[7m"
-				}
-				// Double each character in the cell for horizontal scaling
+				bgColor := squareColor(row, col, row == cursorRow && col == cursorCol,
+					selected && row == selectedRow && col == selectedCol)
 				for _, ch := range cell {
-					fmt.Fprintf(v, "%s%s%s%c%s", fgColor, bgColor, cursorAttr, ch, reset)
+					fmt.Fprintf(v, "%s%s%c%s", fgColor, bgColor, ch, reset)
 				}
 			}
 			fmt.Fprintln(v)
@@ -450,21 +473,23 @@ func (b ChessBoard) ToFEN(turn Color) string {
 	if turn == Black {
 		turnStr = "b"
 	}
-	// Compute castling rights (simple check: if king/rook are on original squares)
+	// Compute castling rights (simple check: if king/rook are on original squares).
+	// For Chess960 this yields X-FEN, where K and Q refer to the outermost rooks.
 	castle := ""
-	if b[7][4].Type == King && b[7][4].Color == White {
-		if b[7][7].Type == Rook && b[7][7].Color == White {
+	k, qr, kr := castlingFiles.king, castlingFiles.queenRook, castlingFiles.kingRook
+	if b[7][k].Type == King && b[7][k].Color == White {
+		if b[7][kr].Type == Rook && b[7][kr].Color == White {
 			castle += "K"
 		}
-		if b[7][0].Type == Rook && b[7][0].Color == White {
+		if b[7][qr].Type == Rook && b[7][qr].Color == White {
 			castle += "Q"
 		}
 	}
-	if b[0][4].Type == King && b[0][4].Color == Black {
-		if b[0][7].Type == Rook && b[0][7].Color == Black {
+	if b[0][k].Type == King && b[0][k].Color == Black {
+		if b[0][kr].Type == Rook && b[0][kr].Color == Black {
 			castle += "k"
 		}
-		if b[0][0].Type == Rook && b[0][0].Color == Black {
+		if b[0][qr].Type == Rook && b[0][qr].Color == Black {
 			castle += "q"
 		}
 	}
@@ -480,53 +505,93 @@ func (b ChessBoard) ToFEN(turn Color) string {
 	return fen + " " + turnStr + " " + castle + " " + ep + " 0 1"
 }
 
+// Size of the piece art: lines and characters per line.
+const (
+	artLines   = 7
+	artColumns = 14
+)
+
+// pieceFiles are the pieces of a piece set and the names of their art files.
+var pieceFiles = []struct {
+	typ  PieceType
+	name string
+}{
+	{King, "king"}, {Queen, "queen"}, {Rook, "rook"},
+	{Bishop, "bishop"}, {Knight, "knight"}, {Pawn, "pawn"},
+}
+
+// LoadAsciiPieces loads the piece set in pieceFolder. Each piece is read from
+// <piece>.txt, or for one colour from white/<piece>.txt or black/<piece>.txt if
+// the set draws the colours differently. The pieces in use are only replaced
+// if the whole set is valid.
 func LoadAsciiPieces(pieceFolder string) error {
 	// Ensure the piece folder exists
 	if _, err := os.Stat(pieceFolder); os.IsNotExist(err) {
 		return fmt.Errorf("piece folder does not exist: %s", pieceFolder)
 	}
 
-	pieceFiles := map[PieceType]string{
-		King:   pieceFolder + "/king.txt",
-		Queen:  pieceFolder + "/queen.txt",
-		Rook:   pieceFolder + "/rook.txt",
-		Bishop: pieceFolder + "/bishop.txt",
-		Knight: pieceFolder + "/knight.txt",
-		Pawn:   pieceFolder + "/pawn.txt",
-	}
-
-	// Initialize asciiPieces map
-	asciiPieces = make(map[PieceType]map[Color][]string)
-
-	for typ, path := range pieceFiles {
-		lines, err := readAsciiArtFile(path)
-		if err != nil {
-			return err
-		}
-		if asciiPieces[typ] == nil {
-			asciiPieces[typ] = map[Color][]string{}
+	pieces := make(map[PieceType]map[Color][]string)
+	for _, piece := range pieceFiles {
+		pieces[piece.typ] = map[Color][]string{}
+		for _, c := range []struct {
+			color Color
+			dir   string
+		}{{White, "white"}, {Black, "black"}} {
+			path := filepath.Join(pieceFolder, c.dir, piece.name+".txt")
+			if _, err := os.Stat(path); err != nil {
+				path = filepath.Join(pieceFolder, piece.name+".txt")
+			}
+			lines, err := readAsciiArtFile(path)
+			if err != nil {
+				return err
+			}
+			pieces[piece.typ][c.color] = lines
 		}
-		asciiPieces[typ][White] = lines
-		asciiPieces[typ][Black] = lines // Optionally, use different files for Black
 	}
 	// Empty and Undefined pieces
-	empty := make([]string, 7)
+	empty := make([]string, artLines)
 	for i := range empty {
-		empty[i] = "              "
+		empty[i] = strings.Repeat(" ", artColumns)
 	}
-	asciiPieces[Empty] = map[Color][]string{
+	pieces[Empty] = map[Color][]string{
 		White:     empty,
 		Black:     empty,
 		Undefined: empty,
 	}
+	asciiPieces = pieces
 	return nil
 }
 
+// PieceSets returns the sorted names of the piece sets in the directories below
+// root, which hold a king.txt or white/king.txt.
+func PieceSets(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var sets []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		for _, king := range []string{"king.txt", filepath.Join("white", "king.txt")} {
+			if _, err := os.Stat(filepath.Join(root, e.Name(), king)); err == nil {
+				sets = append(sets, e.Name())
+				break
+			}
+		}
+	}
+	sort.Strings(sets)
+	return sets, nil
+}
+
 // GetMoveHistory returns the move history as a slice of strings.
 func GetMoveHistory() []string {
 	return history.GetHistory()
 }
 
+// readAsciiArtFile reads the art of a piece and checks that it is artLines
+// lines of artColumns characters.
 func readAsciiArtFile(path string) ([]string, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -534,9 +599,17 @@ func readAsciiArtFile(path string) ([]string, error) {
 	}
 	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
 	// Remove empty trailing lines
-	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
 		lines = lines[:len(lines)-1]
 	}
+	if len(lines) != artLines {
+		return nil, fmt.Errorf("%s: piece art must be %d lines, got %d", path, artLines, len(lines))
+	}
+	for i, line := range lines {
+		if n := utf8.RuneCountInString(line); n != artColumns {
+			return nil, fmt.Errorf("%s: line %d must be %d characters wide, got %d", path, i+1, artColumns, n)
+		}
+	}
 	return lines, nil
 }
 
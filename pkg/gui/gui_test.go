@@ -1,6 +1,9 @@
 package gui
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -142,6 +145,21 @@ func TestGetEnPassantSquare_NotDoublePawnMove(t *testing.T) {
 	}
 }
 
+func TestSetEnPassantSquareFromFEN(t *testing.T) {
+	defer NewChessBoard()
+	SetEnPassantSquareFromFEN("rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3")
+	if row, col := GetEnPassantSquare(); row != 2 || col != 5 {
+		t.Errorf("Expected en passant square at (2,5), got (%d,%d)", row, col)
+	}
+	if fen := NewChessBoardFromFEN("rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3").ToFEN(White); !strings.Contains(fen, " f6 ") {
+		t.Errorf("Expected the en passant square in the FEN, got %s", fen)
+	}
+	SetEnPassantSquareFromFEN("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if row, col := GetEnPassantSquare(); row != -1 || col != -1 {
+		t.Errorf("Expected no en passant square, got (%d,%d)", row, col)
+	}
+}
+
 func TestGetEnPassantSquare_ErrorHandling(t *testing.T) {
 	// Directly test initial state
 	row, col := GetEnPassantSquare()
@@ -251,3 +269,256 @@ func TestChessBoard_Reset(t *testing.T) {
 		t.Error("Expected e4 to be empty after reset")
 	}
 }
+
+// Test Chess960 start positions against the Scharnagl numbering
+func TestChess960BackRank_KnownPositions(t *testing.T) {
+	tests := map[int][8]PieceType{
+		0:   {Bishop, Bishop, Queen, Knight, Knight, Rook, King, Rook},
+		518: {Rook, Knight, Bishop, Queen, King, Bishop, Knight, Rook},
+		959: {Rook, King, Rook, Knight, Knight, Queen, Bishop, Bishop},
+	}
+	for sp, want := range tests {
+		got, err := Chess960BackRank(sp)
+		if err != nil {
+			t.Fatalf("Unexpected error for start position %d: %v", sp, err)
+		}
+		if got != want {
+			t.Errorf("Start position %d: expected %v, got %v", sp, want, got)
+		}
+	}
+	if _, err := Chess960BackRank(960); err == nil {
+		t.Error("Expected error for start position 960")
+	}
+}
+
+// Test Chess960 castling with the king moving onto its own rook
+func TestMovePiece_Chess960Castle(t *testing.T) {
+	board, err := NewChess960Board(959) // RKRNNQBB
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer UseStandardCastling()
+	for col := 3; col < 7; col++ {
+		board[7][col] = Piece{Color: Undefined, Type: Empty}
+	}
+	if fen := board.ToFEN(White); fen != "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKR4B w KQkq - 0 1" {
+		t.Errorf("Unexpected FEN: %s", fen)
+	}
+	ok := board.MovePiece(7, 1, 7, 2, White) // king b1 onto rook c1
+	if !ok {
+		t.Fatal("Expected Chess960 kingside castle to succeed")
+	}
+	if board[7][6].Type != King || board[7][5].Type != Rook {
+		t.Error("Expected king on g1 and rook on f1 after castling")
+	}
+	if board[7][1].Type != Empty || board[7][2].Type != Empty {
+		t.Error("Expected b1 and c1 to be empty after castling")
+	}
+}
+
+// Test that Chess960 castling is rejected when pieces are in the way
+func TestMovePiece_Chess960CastleBlocked(t *testing.T) {
+	board, _ := NewChess960Board(959)
+	defer UseStandardCastling()
+	if board.MovePiece(7, 1, 7, 2, White) {
+		t.Error("Expected castling through pieces to fail")
+	}
+}
+
+// Test promoting a pawn to a knight
+func TestMovePiecePromoting_Underpromotion(t *testing.T) {
+	board := NewChessBoardFromFEN("8/4P1k1/8/8/8/8/8/4K3 w - - 0 1")
+	if !board.MovePiecePromoting(1, 4, 0, 4, White, Knight) {
+		t.Fatal("Expected e7-e8 to succeed")
+	}
+	if board[0][4].Type != Knight || board[0][4].Color != White {
+		t.Errorf("Expected a white knight on e8, got %+v", board[0][4])
+	}
+}
+
+// Test the king move that castles in Chess960
+func TestChess960CastlingMove(t *testing.T) {
+	if _, err := NewChess960Board(959); err != nil { // RKRNNQBB
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer UseStandardCastling()
+	if row, from, to := Chess960CastlingMove(Black, true); row != 0 || from != 1 || to != 2 {
+		t.Errorf("Kingside: got row %d, %d -> %d", row, from, to)
+	}
+	if row, from, to := Chess960CastlingMove(White, false); row != 7 || from != 1 || to != 0 {
+		t.Errorf("Queenside: got row %d, %d -> %d", row, from, to)
+	}
+}
+
+// Test mapping view positions to squares
+func TestSquareAt(t *testing.T) {
+	tests := []struct {
+		x, y     int
+		flipped  bool
+		row, col int
+		ok       bool
+	}{
+		{2, 1, false, 0, 0, true},    // a8, top left
+		{15, 7, false, 0, 0, true},   // a8, bottom right
+		{16, 8, false, 1, 1, true},   // b7
+		{113, 56, false, 7, 7, true}, // h1
+		{2, 1, true, 7, 7, true},     // h1 when flipped
+		{1, 1, false, 0, 0, false},   // rank label
+		{2, 0, false, 0, 0, false},   // file labels
+		{114, 10, false, 0, 0, false},
+		{10, 57, false, 0, 0, false},
+	}
+	for _, tt := range tests {
+		row, col, ok := SquareAt(tt.x, tt.y, tt.flipped)
+		if ok != tt.ok || (ok && (row != tt.row || col != tt.col)) {
+			t.Errorf("SquareAt(%d, %d, %v) = %d, %d, %v; want %d, %d, %v", tt.x, tt.y, tt.flipped, row, col, ok, tt.row, tt.col, tt.ok)
+		}
+	}
+}
+
+// Test choosing the largest board that fits
+func TestChooseRenderMode(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          RenderMode
+	}{
+		{200, 80, RenderFull},
+		{114, 57, RenderFull},
+		{113, 57, RenderMedium},
+		{120, 40, RenderMedium},
+		{80, 20, RenderCompact},
+		{10, 5, RenderCompact},
+	}
+	for _, tt := range tests {
+		if got := ChooseRenderMode(tt.width, tt.height); got != tt.want {
+			t.Errorf("ChooseRenderMode(%d, %d) = %d, want %d", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+// Test scaling piece art down with half blocks
+func TestHalfBlocks(t *testing.T) {
+	art := []string{
+		"      ██      ",
+		"     ████     ",
+		"              ",
+	}
+	got := halfBlocks(art)
+	want := []string{"  ▄█▄  ", "       "}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("halfBlocks = %q, want %q", got, want)
+	}
+}
+
+// Test mapping view positions to squares of the compact board
+func TestSquareAt_Compact(t *testing.T) {
+	BoardRenderMode = RenderCompact
+	defer func() { BoardRenderMode = RenderFull }()
+	if row, col, ok := SquareAt(3, 1, false); !ok || row != 0 || col != 0 {
+		t.Errorf("Expected a8, got %d, %d, %v", row, col, ok)
+	}
+	if row, col, ok := SquareAt(17, 8, false); !ok || row != 7 || col != 7 {
+		t.Errorf("Expected h1, got %d, %d, %v", row, col, ok)
+	}
+	if _, _, ok := SquareAt(18, 8, false); ok {
+		t.Error("Expected no square right of the board")
+	}
+}
+
+// writeArt writes piece art files of the given lines for all pieces into dir.
+func writeArt(t *testing.T, dir string, lines []string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pieceFiles {
+		if err := os.WriteFile(filepath.Join(dir, p.name+".txt"), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Test loading piece sets with shared and separate art per colour
+func TestLoadAsciiPieces_Colors(t *testing.T) {
+	solid := []string{
+		"              ", "      ██      ", "     ████     ", "      ██      ",
+		"     ████     ", "     ████     ", "   ████████   ",
+	}
+	shaded := make([]string, len(solid))
+	for i, line := range solid {
+		shaded[i] = strings.ReplaceAll(line, "█", "▒")
+	}
+	root := t.TempDir()
+	writeArt(t, filepath.Join(root, "plain"), solid)
+	writeArt(t, filepath.Join(root, "split", "white"), shaded)
+	writeArt(t, filepath.Join(root, "split", "black"), solid)
+	os.MkdirAll(filepath.Join(root, "empty"), 0755)
+
+	if err := LoadAsciiPieces(filepath.Join(root, "split")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if asciiPieces[King][White][1] != shaded[1] || asciiPieces[King][Black][1] != solid[1] {
+		t.Error("Expected separate art for white and black")
+	}
+	if err := LoadAsciiPieces(filepath.Join(root, "plain")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if asciiPieces[Queen][White][1] != solid[1] || asciiPieces[Queen][Black][1] != solid[1] {
+		t.Error("Expected the same art for both colours")
+	}
+
+	sets, err := PieceSets(root)
+	if err != nil || strings.Join(sets, " ") != "plain split" {
+		t.Errorf("PieceSets = %v, %v; want plain split", sets, err)
+	}
+}
+
+// Test that piece art of the wrong size is rejected and keeps the loaded set
+func TestLoadAsciiPieces_InvalidSize(t *testing.T) {
+	good := make([]string, 7)
+	for i := range good {
+		good[i] = strings.Repeat(" ", 14)
+	}
+	root := t.TempDir()
+	writeArt(t, filepath.Join(root, "good"), good)
+	writeArt(t, filepath.Join(root, "short"), good[:6])
+	wide := append([]string(nil), good...)
+	wide[2] = strings.Repeat("█", 15)
+	writeArt(t, filepath.Join(root, "wide"), wide)
+
+	if err := LoadAsciiPieces(filepath.Join(root, "good")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	loaded := asciiPieces
+	if err := LoadAsciiPieces(filepath.Join(root, "short")); err == nil || !strings.Contains(err.Error(), "must be 7 lines, got 6") {
+		t.Errorf("Expected a line count error, got %v", err)
+	}
+	if err := LoadAsciiPieces(filepath.Join(root, "wide")); err == nil || !strings.Contains(err.Error(), "line 3 must be 14 characters wide, got 15") {
+		t.Errorf("Expected a width error, got %v", err)
+	}
+	if asciiPieces[King] == nil || len(asciiPieces) != len(loaded) {
+		t.Error("Expected the loaded set to be kept")
+	}
+}
+
+// Test that the bundled piece sets are valid
+func TestLoadAsciiPieces_Bundled(t *testing.T) {
+	root := filepath.Join("..", "..", "assets", "pieces")
+	sets, err := PieceSets(root)
+	if err != nil || len(sets) < 2 {
+		t.Fatalf("PieceSets = %v, %v", sets, err)
+	}
+	for _, set := range sets {
+		if err := LoadAsciiPieces(filepath.Join(root, set)); err != nil {
+			t.Errorf("Piece set %s: %v", set, err)
+		}
+	}
+}
+
+// Test that scaling down keeps shaded art shaded
+func TestHalfBlocks_Shaded(t *testing.T) {
+	got := halfBlocks([]string{"      ▒▒      ", "     ▒▒▒▒     "})
+	if len(got) != 1 || got[0] != "  ▒▒▒  " {
+		t.Errorf("halfBlocks = %q", got)
+	}
+}
@@ -0,0 +1,190 @@
+package gui
+
+import (
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/theme"
+)
+
+// RenderMode selects how large the board is drawn.
+type RenderMode int
+
+const (
+	RenderFull    RenderMode = iota // The ASCII art pieces, 14x7 characters per square
+	RenderMedium                    // The ASCII art scaled down with half blocks, 7x4 per square
+	RenderCompact                   // One Unicode piece symbol per square, like PrintBoard
+)
+
+// RenderModes lists the render modes from the largest to the smallest.
+var RenderModes = []RenderMode{RenderFull, RenderMedium, RenderCompact}
+
+// BoardRenderMode is the mode RenderToView draws the board in.
+var BoardRenderMode = RenderFull
+
+// BoardPalette holds the colours the board is drawn with.
+var BoardPalette, _ = theme.Builtin[theme.Default].Palette(theme.Colors16)
+
+// Square is a square of the board by row and column.
+type Square struct {
+	Row, Col int
+}
+
+// Highlights are the squares marked on the board besides the cursor and the
+// selection.
+type Highlights struct {
+	LastMove []Square // Squares the last move came from and went to
+	Check    []Square // Square of a king in check
+}
+
+// BoardHighlights are the squares RenderToView marks.
+var BoardHighlights Highlights
+
+// squareColor returns the background escape sequence of a square: the square's
+// colour or the colour of its strongest highlight.
+func squareColor(row, col int, cursor, selection bool) string {
+	p := BoardPalette
+	color := p.LightSquare
+	if (row+col)%2 == 1 {
+		color = p.DarkSquare
+	}
+	for _, h := range []struct {
+		marked bool
+		color  string
+	}{
+		{containsSquare(BoardHighlights.LastMove, row, col), p.LastMove},
+		{containsSquare(BoardHighlights.Check, row, col), p.Check},
+		{selection, p.Selection},
+		{cursor, p.Cursor},
+	} {
+		if h.marked && h.color != "" {
+			color = h.color
+		}
+	}
+	return color
+}
+
+func containsSquare(squares []Square, row, col int) bool {
+	for _, s := range squares {
+		if s.Row == row && s.Col == col {
+			return true
+		}
+	}
+	return false
+}
+
+// labelWidth is the width of the rank labels left of the squares.
+const labelWidth = 2
+
+// pieceSymbols are the Unicode symbols of the compact board.
+var pieceSymbols = map[PieceType]rune{
+	King:   '♚',
+	Queen:  '♛',
+	Rook:   '♜',
+	Bishop: '♝',
+	Knight: '♞',
+	Pawn:   '♟',
+}
+
+// squareSize returns the width and height in characters of a square.
+func (m RenderMode) squareSize() (width, height int) {
+	switch m {
+	case RenderMedium:
+		return 7, 4
+	case RenderCompact:
+		return 2, 1
+	}
+	return artColumns, artLines
+}
+
+// BoardSize returns the width and height in characters of the board drawn in
+// the mode, including the file and rank labels.
+func (m RenderMode) BoardSize() (width, height int) {
+	w, h := m.squareSize()
+	return labelWidth + 8*w, 1 + 8*h
+}
+
+// ChooseRenderMode returns the largest mode whose board fits into width and
+// height, the compact mode if none does.
+func ChooseRenderMode(width, height int) RenderMode {
+	for _, m := range RenderModes {
+		if w, h := m.BoardSize(); w <= width && h <= height {
+			return m
+		}
+	}
+	return RenderCompact
+}
+
+// squareLines returns the lines drawn for a piece in the mode.
+func (m RenderMode) squareLines(piece Piece) []string {
+	art := asciiPieces[piece.Type][piece.Color]
+	switch m {
+	case RenderMedium:
+		return halfBlocks(art)
+	case RenderCompact:
+		symbol, ok := pieceSymbols[piece.Type]
+		if !ok {
+			return []string{"  "}
+		}
+		return []string{" " + string(symbol)}
+	}
+	return art
+}
+
+// halfBlocks scales ASCII art down to half its width and height. Two columns
+// become one character and two lines become one, drawn with half blocks. Art
+// drawn with other characters than full blocks, such as shades, keeps them.
+func halfBlocks(art []string) []string {
+	at := func(line []rune, col int) rune {
+		if col < len(line) {
+			return line[col]
+		}
+		return ' '
+	}
+	var lines []string
+	for y := 0; y < len(art); y += 2 {
+		top := []rune(art[y])
+		var bottom []rune
+		if y+1 < len(art) {
+			bottom = []rune(art[y+1])
+		}
+		var sb strings.Builder
+		for x := 0; x < artColumns; x += 2 {
+			fill := '█'
+			for _, ch := range []rune{at(top, x), at(top, x+1), at(bottom, x), at(bottom, x+1)} {
+				if ch != ' ' && ch != '█' {
+					fill = ch
+				}
+			}
+			t := at(top, x) != ' ' || at(top, x+1) != ' '
+			b := at(bottom, x) != ' ' || at(bottom, x+1) != ' '
+			switch {
+			case t && b, (t || b) && fill != '█':
+				sb.WriteRune(fill)
+			case t:
+				sb.WriteRune('▀')
+			case b:
+				sb.WriteRune('▄')
+			default:
+				sb.WriteRune(' ')
+			}
+		}
+		lines = append(lines, sb.String())
+	}
+	return lines
+}
+
+// SquareAt returns the board row and column of the square drawn at column x and
+// line y of the board view. ok is false outside the squares.
+func SquareAt(x, y int, flipped bool) (row, col int, ok bool) {
+	w, h := BoardRenderMode.squareSize()
+	x -= labelWidth
+	y--
+	if x < 0 || y < 0 || x >= 8*w || y >= 8*h {
+		return 0, 0, false
+	}
+	row, col = y/h, x/w
+	if flipped {
+		row, col = 7-row, 7-col
+	}
+	return row, col, true
+}
@@ -0,0 +1,86 @@
+package history
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/corentings/chess"
+)
+
+// newGame returns a game at the given start position. The chess library only
+// supports standard castling, so castling rights are dropped for Chess960 games
+// and Chess960 castles are applied by chess960Castle instead.
+func newGame(fen string, isChess960 bool) *chess.Game {
+	if fen == "" {
+		return chess.NewGame()
+	}
+	if isChess960 {
+		fen = WithoutCastling(fen)
+	}
+	fenFunc, err := chess.FEN(fen)
+	if err != nil {
+		return chess.NewGame()
+	}
+	return chess.NewGame(fenFunc)
+}
+
+// WithoutCastling replaces the castling field of a FEN string with "-". The
+// chess library only knows standard castling, so Chess960 castling rights are
+// left out of the positions it is given.
+func WithoutCastling(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 2 {
+		fields[2] = "-"
+	}
+	return strings.Join(fields, " ")
+}
+
+// chess960Castle applies a castling move in the UCI Chess960 encoding (king takes
+// own rook) and returns its SAN and a game continuing from the new position.
+func chess960Castle(game *chess.Game, raw string) (string, *chess.Game, bool) {
+	move, err := chess.UCINotation{}.Decode(nil, raw)
+	if err != nil {
+		return "", nil, false
+	}
+	pos := game.Position()
+	board := pos.Board()
+	king, rook := board.Piece(move.S1()), board.Piece(move.S2())
+	if king.Type() != chess.King || rook.Type() != chess.Rook || king.Color() != rook.Color() ||
+		king.Color() != pos.Turn() || move.S1().Rank() != move.S2().Rank() {
+		return "", nil, false
+	}
+
+	// The king ends on the g or c file and the rook next to it, towards the centre
+	rank := move.S1().Rank()
+	san, kingFile, rookFile := "O-O", chess.FileG, chess.FileF
+	if move.S2().File() < move.S1().File() {
+		san, kingFile, rookFile = "O-O-O", chess.FileC, chess.FileD
+	}
+	squares := board.SquareMap()
+	delete(squares, move.S1())
+	delete(squares, move.S2())
+	squares[chess.NewSquare(kingFile, rank)] = king
+	squares[chess.NewSquare(rookFile, rank)] = rook
+
+	// Castling is no capture or pawn move, so the halfmove clock goes on
+	fields := strings.Fields(game.FEN())
+	halfmove, _ := strconv.Atoi(fields[4])
+	fullmove, _ := strconv.Atoi(fields[5])
+	turn := "b"
+	if pos.Turn() == chess.Black {
+		turn = "w"
+		fullmove++
+	}
+	fenFunc, err := chess.FEN(fmt.Sprintf("%s %s - - %d %d", chess.NewBoard(squares), turn, halfmove+1, fullmove))
+	if err != nil {
+		return "", nil, false
+	}
+	next := chess.NewGame(fenFunc)
+	if next.Position().Status() == chess.Checkmate {
+		san += "#"
+	} else if IsInCheck(next) {
+		san += "+"
+	}
+	return san, next, true
+}
@@ -3,29 +3,119 @@ package history
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/RubikNube/TerminalChess/pkg/notation"
+	"github.com/RubikNube/TerminalChess/pkg/opening"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
 	"github.com/corentings/chess"
 )
 
 var (
-	mu    sync.Mutex
-	moves []string
+	mu       sync.Mutex
+	records  []Record
+	startFEN string             // FEN of the start position, empty for the standard start position
+	chess960 bool               // Whether castling follows the Chess960 rules
+	style    = notation.Default // Notation of GetMoveHistorySAN
+
+	// Cached state, so the game is never replayed from the start
+	tip      *chess.Game       // Game at the position after the last move, nil before the first move
+	sanLines []string          // Cached result of GetMoveHistorySAN, nil if out of date
+	openings []opening.Opening // Opening of the start position and after each move classified so far
 )
 
-// AddMove appends a move in algebraic notation to the history.
+// SetStartPosition sets the position the recorded moves start from. An empty FEN
+// selects the standard start position. With isChess960 set, castling moves are
+// recorded as the king moving onto its own rook.
+func SetStartPosition(fen string, isChess960 bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	startFEN = fen
+	chess960 = isChess960
+	// Recorded moves are derived from the start position
+	old := records
+	records, tip, sanLines, openings = nil, nil, nil, nil
+	for _, r := range old {
+		add(r.UCI).Annotation = r.Annotation
+	}
+}
+
+// GetStartPosition returns the FEN of the start position, or an empty string for
+// the standard start position.
+func GetStartPosition() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return startFEN
+}
+
+// AddMove appends a move in UCI notation to the history. Moves that cannot be
+// applied to the position are kept as they are.
 func AddMove(move string) {
 	mu.Lock()
 	defer mu.Unlock()
-	moves = append(moves, move)
+	add(move)
+}
+
+// add appends the record of a move and returns it. mu must be held.
+func add(move string) *Record {
+	// Older versions marked en passant captures in the move itself
+	move = strings.TrimSuffix(move, " e.p.")
+	if tip == nil {
+		tip = newGame(startFEN, chess960)
+	}
+	var r Record
+	r, tip = newRecord(tip, move, chess960)
+	records = append(records, r)
+	sanLines = nil
+	return &records[len(records)-1]
+}
+
+// GetRecords returns a copy of the recorded moves.
+func GetRecords() []Record {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Record(nil), records...)
+}
+
+// GetRecord returns the record of the move at the given ply (0-based).
+func GetRecord(ply int) (Record, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if ply < 0 || ply >= len(records) {
+		return Record{}, false
+	}
+	return records[ply], true
+}
+
+// SetAnnotation attaches comments, NAGs and commands to the move at the given
+// ply (0-based). It returns false if there is no such move.
+func SetAnnotation(ply int, a pgn.Annotation) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if ply < 0 || ply >= len(records) {
+		return false
+	}
+	records[ply].Annotation = a
+	sanLines = nil
+	return true
+}
+
+// GetAnnotation returns the annotation of the move at the given ply (0-based).
+func GetAnnotation(ply int) pgn.Annotation {
+	r, _ := GetRecord(ply)
+	return r.Annotation
 }
 
-// GetHistory returns a copy of the move history.
+// GetHistory returns the recorded moves in UCI notation.
 func GetHistory() []string {
 	mu.Lock()
 	defer mu.Unlock()
-	history := make([]string, len(moves))
-	copy(history, moves)
+	history := make([]string, len(records))
+	for i, r := range records {
+		history[i] = r.UCI
+	}
 	return history
 }
 
@@ -33,7 +123,7 @@ func GetHistory() []string {
 func ClearHistory() {
 	mu.Lock()
 	defer mu.Unlock()
-	moves = nil
+	records, tip, sanLines, openings = nil, nil, nil, nil
 }
 
 // IsInCheck returns true if the side to move is in check in the given game position.
@@ -186,63 +276,191 @@ func sign(x int) int {
 	return 0
 }
 
-// GetMoveHistorySAN returns the move history as a slice of formatted strings in standard algebraic notation,
-// including + for check and # for checkmate.
+// GetMoveHistorySAN returns the move history as a slice of formatted strings in
+// the notation selected with SetNotation (standard algebraic notation by
+// default), including + for check and # for checkmate.
+// Moves are followed by the glyphs of their NAGs, e.g. "Nf6?!". The lines are
+// cached until the next change of the history.
 func GetMoveHistorySAN() []string {
-	rawMoves := GetHistory()
-	game := chess.NewGame()
-	var lines []string
-	for i := 0; i < len(rawMoves); i += 2 {
-		moveNum := i/2 + 1
-		var whiteSAN, blackSAN string
-
-		// White move
-		if i < len(rawMoves) {
-			move, err := chess.UCINotation{}.Decode(game.Position(), rawMoves[i])
-			if err == nil {
-				whiteSAN = chess.AlgebraicNotation{}.Encode(game.Position(), move)
-				// Annotate with e.p. for en passant
-				if move.HasTag(chess.EnPassant) {
-					whiteSAN += " e.p."
-				}
-				game.Move(move)
-				// Annotate with # for checkmate, + for check
-				if game.Outcome() != chess.NoOutcome && game.Method() == chess.Checkmate {
-					whiteSAN += "#"
-				} else if IsInCheck(game) {
-					whiteSAN += "+"
-				}
+	mu.Lock()
+	defer mu.Unlock()
+	if sanLines == nil {
+		sanLines = []string{}
+		first := 0
+		if number, black := moveNumber(0); black && len(records) > 0 {
+			// A game set up with Black to move starts with a line of its own
+			sanLines = append(sanLines, fmt.Sprintf("%d... %s", number, records[0].display(style)+records[0].Glyphs()))
+			first = 1
+		}
+		for i := first; i < len(records); i += 2 {
+			moveNum, _ := moveNumber(i)
+			whiteSAN := records[i].display(style) + records[i].Glyphs()
+			if i+1 < len(records) {
+				sanLines = append(sanLines, fmt.Sprintf("%d. %s %s", moveNum, whiteSAN, records[i+1].display(style)+records[i+1].Glyphs()))
 			} else {
-				whiteSAN = rawMoves[i]
+				sanLines = append(sanLines, fmt.Sprintf("%d. %s", moveNum, whiteSAN))
 			}
 		}
+	}
+	return append([]string(nil), sanLines...)
+}
 
-		// Black move
-		if i+1 < len(rawMoves) {
-			move, err := chess.UCINotation{}.Decode(game.Position(), rawMoves[i+1])
-			if err == nil {
-				blackSAN = chess.AlgebraicNotation{}.Encode(game.Position(), move)
-				// Annotate with e.p. for en passant
-				if move.HasTag(chess.EnPassant) {
-					blackSAN += " e.p."
-				}
-				game.Move(move)
-				// Annotate with # for checkmate, + for check
-				if game.Outcome() != chess.NoOutcome && game.Method() == chess.Checkmate {
-					blackSAN += "#"
-				} else if IsInCheck(game) {
-					blackSAN += "+"
-				}
-			} else {
-				blackSAN = rawMoves[i+1]
-			}
+// Opening returns the opening of the game up to and including the given ply
+// (0-based), -1 for the start position. It is the opening of the latest
+// position found in the ECO table, see opening.Classify. Chess960 games have
+// no ECO classification. Openings are classified once per ply.
+func Opening(ply int) (opening.Opening, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if chess960 {
+		return opening.Opening{}, false
+	}
+	ply = min(ply, len(records)-1)
+	if openings == nil {
+		o, _ := opening.Lookup(newGame(startFEN, chess960).FEN())
+		openings = []opening.Opening{o}
+	}
+	for i := len(openings) - 1; i <= ply; i++ {
+		o, ok := opening.Lookup(records[i].FEN)
+		if !ok {
+			o = openings[i]
 		}
+		openings = append(openings, o)
+	}
+	o := openings[max(ply+1, 0)]
+	return o, o.ECO != ""
+}
 
-		if blackSAN != "" {
-			lines = append(lines, fmt.Sprintf("%d. %s %s", moveNum, whiteSAN, blackSAN))
-		} else {
-			lines = append(lines, fmt.Sprintf("%d. %s", moveNum, whiteSAN))
+// MoveNumber returns the move number of the move at the given ply (0-based)
+// and whether Black plays it. Games set up from a FEN may start with Black to
+// move or at a later move number.
+func MoveNumber(ply int) (number int, black bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	return moveNumber(ply)
+}
+
+// MovePly returns the ply (0-based) of the move with the given number played
+// by White or Black. It is negative for moves before the start position.
+func MovePly(number int, black bool) int {
+	mu.Lock()
+	defer mu.Unlock()
+	ply := 2*number - 2 - startPlies()
+	if black {
+		ply++
+	}
+	return ply
+}
+
+// HistoryLine returns the index of the line of GetMoveHistorySAN that shows
+// the move at the given ply.
+func HistoryLine(ply int) int {
+	mu.Lock()
+	defer mu.Unlock()
+	number, _ := moveNumber(ply)
+	first, _ := moveNumber(0)
+	return number - first
+}
+
+// moveNumber implements MoveNumber. mu must be held.
+func moveNumber(ply int) (int, bool) {
+	n := ply + startPlies()
+	return n/2 + 1, n%2 == 1
+}
+
+// startPlies returns the number of plies from White's first move to the start
+// position. mu must be held.
+func startPlies() int {
+	fields := strings.Fields(startFEN)
+	if len(fields) < 6 {
+		return 0
+	}
+	plies := 0
+	if fullmove, err := strconv.Atoi(fields[5]); err == nil && fullmove > 1 {
+		plies = 2 * (fullmove - 1)
+	}
+	if fields[1] == "b" {
+		plies++
+	}
+	return plies
+}
+
+// SetNotation selects the notation of the move history lines.
+func SetNotation(n notation.Notation) {
+	mu.Lock()
+	defer mu.Unlock()
+	style = n
+	sanLines = nil
+}
+
+// GetNotation returns the notation of the move history lines.
+func GetNotation() notation.Notation {
+	mu.Lock()
+	defer mu.Unlock()
+	return style
+}
+
+// GetMovesSAN returns the standard algebraic notation of every ply, suitable for
+// PGN movetext. Moves that cannot be applied are returned unchanged.
+func GetMovesSAN() []string {
+	recs := GetRecords()
+	sans := make([]string, len(recs))
+	for i, r := range recs {
+		sans[i] = r.UCI
+		if r.Legal() {
+			sans[i] = r.SAN
 		}
 	}
-	return lines
+	return sans
+}
+
+// GetResult returns the PGN result of the recorded game: "1-0", "0-1",
+// "1/2-1/2" or "*" while the game is in progress.
+func GetResult() string {
+	mu.Lock()
+	defer mu.Unlock()
+	if tip == nil {
+		return chess.NoOutcome.String()
+	}
+	// The game restarts from the new position after a Chess960 castle, which
+	// forgets the earlier positions
+	if tip.Outcome() == chess.NoOutcome && repetitions() >= 5 {
+		return chess.Draw.String()
+	}
+	return tip.Outcome().String()
+}
+
+// repetitions returns how often the position after the last move occurred in
+// the game, the start position included. mu must be held.
+func repetitions() int {
+	position := func(fen string) string {
+		// Pieces, side to move, castling rights and en passant square
+		return strings.Join(strings.Fields(fen)[:4], " ")
+	}
+	last := position(tip.FEN())
+	count := 0
+	if position(newGame(startFEN, chess960).FEN()) == last {
+		count++
+	}
+	for _, r := range records {
+		if r.Legal() && position(r.FEN) == last {
+			count++
+		}
+	}
+	return count
+}
+
+// GetPositionFEN returns the FEN of the position after the given ply (0-based),
+// the start position for a negative ply and the last position for a ply past
+// the end. Moves that cannot be applied leave the position unchanged.
+func GetPositionFEN(ply int) string {
+	mu.Lock()
+	defer mu.Unlock()
+	if ply >= len(records) {
+		ply = len(records) - 1
+	}
+	if ply < 0 {
+		return newGame(startFEN, chess960).FEN()
+	}
+	return records[ply].FEN
 }
@@ -3,6 +3,8 @@ package history
 import (
 	"testing"
 
+	"github.com/RubikNube/TerminalChess/pkg/notation"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
 	"github.com/corentings/chess"
 )
 
@@ -61,3 +63,187 @@ func TestIsInCheck_NoCheck(t *testing.T) {
 		t.Error("Expected no check in starting position")
 	}
 }
+
+func TestGetMovesSAN_Chess960Castle(t *testing.T) {
+	ClearHistory()
+	SetStartPosition("rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKR4B w KQkq - 0 1", true)
+	defer SetStartPosition("", false)
+	AddMove("b1c1")
+	AddMove("e7e5")
+	san := GetMovesSAN()
+	if len(san) != 2 || san[0] != "O-O" || san[1] != "e5" {
+		t.Errorf("Expected [O-O e5], got %v", san)
+	}
+	if fen := GetPositionFEN(0); fen != "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/R4RKB b - - 1 1" {
+		t.Errorf("Unexpected position after castling: %s", fen)
+	}
+}
+
+func TestChess960Castle_KeepsCountersAndRepetitions(t *testing.T) {
+	ClearHistory()
+	SetStartPosition("rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKR4B w KQkq - 4 7", true)
+	defer SetStartPosition("", false)
+	AddMove("b1c1")
+	AddMove("e8f6")
+	if fen := GetPositionFEN(1); fen != "rkrn1qbb/pppppppp/5n2/8/8/8/PPPPPPPP/R4RKB w - - 6 8" {
+		t.Errorf("Expected the move counters to go on after castling, got %s", fen)
+	}
+
+	// The king castles and walks back, so the start position repeats across castles
+	ClearHistory()
+	SetStartPosition("n3k3/8/8/8/8/8/8/5K1R w - - 0 1", true)
+	cycle := []string{"f1h1", "a8b6", "g1f2", "b6a8", "f1h1", "a8b6", "f2f1", "b6a8"}
+	for i := 0; i < 4; i++ {
+		if result := GetResult(); result != "*" {
+			t.Fatalf("Expected no result after %d repetitions, got %s", i+1, result)
+		}
+		for _, move := range cycle {
+			AddMove(move)
+		}
+	}
+	if result := GetResult(); result != "1/2-1/2" {
+		t.Errorf("Expected a draw by fivefold repetition across castles, got %s", result)
+	}
+}
+
+func TestGetPositionFEN_StartFEN(t *testing.T) {
+	ClearHistory()
+	SetStartPosition("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", false)
+	defer SetStartPosition("", false)
+	AddMove("e2e4")
+	if fen := GetPositionFEN(0); fen != "4k3/8/8/8/4P3/8/8/4K3 b - e3 0 1" {
+		t.Errorf("Unexpected position: %s", fen)
+	}
+	if result := GetResult(); result != "*" {
+		t.Errorf("Expected result *, got %s", result)
+	}
+}
+
+func TestAnnotations_ShownInHistory(t *testing.T) {
+	ClearHistory()
+	AddMove("e2e4")
+	AddMove("e7e5")
+	if !SetAnnotation(1, pgn.Annotation{NAGs: []int{6}, Comment: "Risky"}) {
+		t.Fatal("Expected annotation to be set")
+	}
+	if SetAnnotation(2, pgn.Annotation{Comment: "No such move"}) {
+		t.Error("Expected annotation beyond the last move to be rejected")
+	}
+	san := GetMoveHistorySAN()
+	if len(san) != 1 || san[0] != "1. e4 e5?!" {
+		t.Errorf("Expected \"1. e4 e5?!\", got %v", san)
+	}
+	if GetAnnotation(1).Comment != "Risky" {
+		t.Errorf("Expected comment to be kept, got %q", GetAnnotation(1).Comment)
+	}
+	ClearHistory()
+	if !GetAnnotation(1).IsEmpty() {
+		t.Error("Expected annotations to be cleared with the history")
+	}
+}
+
+func TestGetRecords_Flags(t *testing.T) {
+	ClearHistory()
+	for _, move := range []string{"e2e4", "a7a6", "e4e5", "d7d5", "e5d6 e.p.", "e8d7", "d6c7", "d7e8", "c7b8q"} {
+		AddMove(move)
+	}
+	recs := GetRecords()
+	if len(recs) != 9 {
+		t.Fatalf("Expected 9 records, got %d", len(recs))
+	}
+	ep := recs[4]
+	if ep.UCI != "e5d6" || ep.SAN != "exd6" || !ep.Has(Capture|EnPassant) || ep.Captured != chess.Pawn {
+		t.Errorf("Unexpected en passant record: %+v", ep)
+	}
+	if recs[3].Flags != 0 || recs[3].Captured != chess.NoPieceType {
+		t.Errorf("Expected a quiet move, got %+v", recs[3])
+	}
+	promo := recs[8]
+	if !promo.Has(Promotion|Capture) || promo.Captured != chess.Knight || promo.SAN != "cxb8=Q" {
+		t.Errorf("Unexpected promotion record: %+v", promo)
+	}
+	if recs[6].SAN != "dxc7" || !recs[6].Has(Capture) || recs[6].Has(Check) {
+		t.Errorf("Expected a capture without check, got %+v", recs[6])
+	}
+	if recs[8].FEN != GetPositionFEN(8) || recs[8].Time.IsZero() {
+		t.Errorf("Expected the position and time to be recorded, got %+v", recs[8])
+	}
+	if san := GetMoveHistorySAN()[2]; san != "3. exd6 e.p. Kd7" {
+		t.Errorf("Expected en passant to be shown, got %q", san)
+	}
+}
+
+func TestLongGame_CachedHistoryAndRepetition(t *testing.T) {
+	ClearHistory()
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+	for i := 0; i < 4; i++ {
+		for _, move := range shuffle {
+			AddMove(move)
+		}
+	}
+	// The start position occurred five times
+	if result := GetResult(); result != "1/2-1/2" {
+		t.Errorf("Expected a draw by repetition, got %s", result)
+	}
+	lines := GetMoveHistorySAN()
+	if len(lines) != 8 || lines[7] != "8. Ng1 Ng8" {
+		t.Fatalf("Unexpected history lines: %v", lines)
+	}
+	// Changes invalidate the cached lines
+	SetAnnotation(15, pgn.Annotation{NAGs: []int{2}})
+	if lines := GetMoveHistorySAN(); lines[7] != "8. Ng1 Ng8?" {
+		t.Errorf("Expected the annotation to be shown, got %q", lines[7])
+	}
+	ClearHistory()
+	if lines := GetMoveHistorySAN(); len(lines) != 0 {
+		t.Errorf("Expected no lines after clear, got %v", lines)
+	}
+	if result := GetResult(); result != "*" {
+		t.Errorf("Expected result * after clear, got %s", result)
+	}
+}
+
+func TestSetNotation_HistoryLines(t *testing.T) {
+	ClearHistory()
+	defer SetNotation(notation.Default)
+	for _, move := range []string{"e2e4", "d7d5", "e4d5", "g8f6"} {
+		AddMove(move)
+	}
+	SetNotation(notation.Notation{Style: notation.Long, Language: "de"})
+	if lines := GetMoveHistorySAN(); lines[0] != "1. e2-e4 d7-d5" || lines[1] != "2. e4xd5 Sg8-f6" {
+		t.Errorf("Unexpected long algebraic lines: %v", lines)
+	}
+	SetNotation(notation.Notation{Style: notation.Figurine})
+	if lines := GetMoveHistorySAN(); lines[1] != "2. exd5 ♘f6" {
+		t.Errorf("Unexpected figurine lines: %v", lines)
+	}
+	// PGN movetext stays in standard algebraic notation
+	if sans := GetMovesSAN(); sans[3] != "Nf6" {
+		t.Errorf("Expected SAN for PGN export, got %v", sans)
+	}
+}
+
+func TestOpening_PerPly(t *testing.T) {
+	ClearHistory()
+	defer ClearHistory()
+	for _, move := range []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "h2h3"} {
+		AddMove(move)
+	}
+	if o, ok := Opening(4); !ok || o.ECO != "C60" {
+		t.Errorf("Expected the Ruy Lopez after 3. Bb5, got %v, %v", o, ok)
+	}
+	// Leaving the known lines keeps the last opening
+	last, ok := Opening(6)
+	if !ok || last.ECO == "" || last.ECO[0] != 'C' {
+		t.Errorf("Expected a Ruy Lopez line after 4. h3, got %v, %v", last, ok)
+	}
+	if o, ok := Opening(100); o != last || !ok {
+		t.Errorf("Expected plies after the end to show the last opening, got %v", o)
+	}
+	// A new game starts a new classification
+	ClearHistory()
+	AddMove("d2d4")
+	if o, ok := Opening(0); !ok || o.ECO[0] != 'A' {
+		t.Errorf("Expected a queen's pawn opening after 1. d4, got %v, %v", o, ok)
+	}
+}
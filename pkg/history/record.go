@@ -0,0 +1,119 @@
+package history
+
+import (
+	"strings"
+	"time"
+
+	"github.com/RubikNube/TerminalChess/pkg/notation"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/corentings/chess"
+)
+
+// MoveFlag describes a property of a recorded move.
+type MoveFlag uint8
+
+const (
+	Check MoveFlag = 1 << iota
+	Checkmate
+	Capture
+	Castle
+	EnPassant
+	Promotion
+)
+
+// Record is a move in the history together with the data derived from it.
+type Record struct {
+	UCI            string          // Move in UCI notation; Chess960 castles are encoded as king takes rook
+	SAN            string          // Standard algebraic notation with + or #, empty if the move could not be applied
+	FEN            string          // Position after the move
+	Captured       chess.PieceType // Type of the captured piece, chess.NoPieceType if none
+	Flags          MoveFlag
+	Time           time.Time // When the move was recorded
+	pgn.Annotation           // Comment, NAGs, clock and other commands
+}
+
+// Has reports whether the record has all of the given flags.
+func (r Record) Has(flags MoveFlag) bool {
+	return r.Flags&flags == flags
+}
+
+// Legal reports whether the move could be applied to the position.
+func (r Record) Legal() bool {
+	return r.SAN != ""
+}
+
+// Format returns the move in the given notation, or the raw move if it could
+// not be applied.
+func (r Record) Format(n notation.Notation) string {
+	return n.Format(r.UCI, r.SAN)
+}
+
+// display returns the move in the given notation, annotated with e.p. for en
+// passant captures.
+func (r Record) display(n notation.Notation) string {
+	move := r.Format(n)
+	if r.Has(EnPassant) && n.Style != notation.UCI {
+		// Keep the check marker at the end of the move
+		base := strings.TrimRight(move, "+#")
+		return base + " e.p." + move[len(base):]
+	}
+	return move
+}
+
+// newRecord applies the move to game and returns its record together with the
+// game continuing after it, which may be game itself. If the move cannot be
+// applied, the record keeps the position of game.
+func newRecord(game *chess.Game, raw string, isChess960 bool) (Record, *chess.Game) {
+	r := Record{UCI: raw, FEN: game.FEN(), Captured: chess.NoPieceType, Time: time.Now()}
+	if isChess960 {
+		if san, next, ok := chess960Castle(game, raw); ok {
+			r.SAN, r.FEN = san, next.FEN()
+			r.Flags |= Castle | checkFlags(san)
+			return r, next
+		}
+	}
+	pos := game.Position()
+	move, err := chess.UCINotation{}.Decode(pos, raw)
+	if err != nil {
+		return r, game
+	}
+	san := chess.AlgebraicNotation{}.Encode(pos, move)
+	captured := pos.Board().Piece(move.S2()).Type()
+	if game.Move(move) != nil {
+		return r, game
+	}
+	// Annotate with # for checkmate, + for check
+	if game.Outcome() != chess.NoOutcome && game.Method() == chess.Checkmate {
+		san += "#"
+	} else if IsInCheck(game) {
+		san += "+"
+	}
+	r.SAN, r.FEN = san, game.FEN()
+	r.Flags |= checkFlags(san)
+	switch {
+	case move.HasTag(chess.EnPassant):
+		r.Flags |= Capture | EnPassant
+		r.Captured = chess.Pawn
+	case captured != chess.NoPieceType:
+		r.Flags |= Capture
+		r.Captured = captured
+	}
+	if move.HasTag(chess.KingSideCastle) || move.HasTag(chess.QueenSideCastle) {
+		r.Flags |= Castle
+	}
+	if move.Promo() != chess.NoPieceType {
+		r.Flags |= Promotion
+	}
+	return r, game
+}
+
+// checkFlags returns the check flags of a SAN move.
+func checkFlags(san string) MoveFlag {
+	switch {
+	case strings.HasSuffix(san, "#"):
+		return Check | Checkmate
+	case strings.HasSuffix(san, "+"):
+		return Check
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+// Package notation writes and reads moves in the notations a player may prefer:
+// standard algebraic notation with English or localized piece letters,
+// figurine algebraic notation, long algebraic notation and UCI.
+package notation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Style selects how moves are written.
+type Style string
+
+const (
+	SAN      Style = "san"      // Standard algebraic notation, e.g. Nf3
+	Figurine Style = "figurine" // Figurine algebraic notation, e.g. ♘f3
+	Long     Style = "long"     // Long algebraic notation, e.g. Ng1-f3
+	UCI      Style = "uci"      // UCI coordinates, e.g. g1f3
+)
+
+// Styles lists the supported styles.
+var Styles = []Style{SAN, Figurine, Long, UCI}
+
+// pieceOrder is the order of the piece letters in Languages.
+const pieceOrder = "KQRBN"
+
+// Languages maps language codes to their piece letters for king, queen, rook,
+// bishop and knight.
+var Languages = map[string]string{
+	"en": "KQRBN",
+	"de": "KDTLS",
+	"fr": "RDTFC",
+	"es": "RDTAC",
+	"it": "RDTAC",
+	"nl": "KDTLP",
+	"pt": "RDTBC",
+	"sv": "KDTLS",
+	"pl": "KHWGS",
+	"cs": "KDVSJ",
+}
+
+// figurines are the piece symbols in pieceOrder.
+var figurines = []rune("♔♕♖♗♘")
+
+// Notation is a style together with the language of its piece letters.
+type Notation struct {
+	Style    Style
+	Language string // Language code of the piece letters, English if empty
+}
+
+// Default is standard algebraic notation with English piece letters.
+var Default = Notation{Style: SAN, Language: "en"}
+
+// New returns the notation for a style and language code, as given in the
+// configuration. Empty values select the defaults.
+func New(style, language string) (Notation, error) {
+	n := Default
+	if style != "" {
+		n.Style = Style(strings.ToLower(style))
+	}
+	if language != "" {
+		n.Language = strings.ToLower(language)
+	}
+	valid := false
+	for _, s := range Styles {
+		valid = valid || s == n.Style
+	}
+	if !valid {
+		return Default, fmt.Errorf("unknown notation style %q", style)
+	}
+	if _, ok := Languages[n.Language]; !ok {
+		return Default, fmt.Errorf("unknown notation language %q", language)
+	}
+	return n, nil
+}
+
+// letters returns the piece letters of the notation.
+func (n Notation) letters() []rune {
+	if letters, ok := Languages[n.Language]; ok {
+		return []rune(letters)
+	}
+	return []rune(Languages["en"])
+}
+
+// piece returns how the notation writes the English piece letter p.
+func (n Notation) piece(p byte) string {
+	i := strings.IndexByte(pieceOrder, p)
+	switch {
+	case i < 0:
+		return string(p)
+	case n.Style == Figurine:
+		return string(figurines[i])
+	}
+	return string(n.letters()[i])
+}
+
+// Format writes a move given in UCI and SAN. san may be empty for moves that
+// could not be applied, which are written as UCI.
+func (n Notation) Format(uci, san string) string {
+	if n.Style == UCI || san == "" {
+		return uci
+	}
+	if strings.HasPrefix(san, "O-O") {
+		return san
+	}
+	base := strings.TrimRight(san, "+#")
+	suffix := san[len(base):]
+
+	// Split off the moving piece and the promotion piece
+	piece := ""
+	if strings.IndexByte(pieceOrder, base[0]) >= 0 {
+		piece, base = n.piece(base[0]), base[1:]
+	}
+	promotion := ""
+	if i := strings.IndexByte(base, '='); i >= 0 && i+1 < len(base) {
+		promotion, base = "="+n.piece(base[i+1]), base[:i]
+	}
+
+	if n.Style == Long && len(uci) >= 4 {
+		sep := "-"
+		if strings.Contains(base, "x") {
+			sep = "x"
+		}
+		return piece + uci[:2] + sep + uci[2:4] + promotion + suffix
+	}
+	return piece + base + promotion + suffix
+}
@@ -0,0 +1,127 @@
+package notation
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		n        Notation
+		uci, san string
+		want     string
+	}{
+		{Default, "g1f3", "Nf3", "Nf3"},
+		{Notation{Style: Figurine}, "g1f3", "Nf3", "♘f3"},
+		{Notation{Style: Long}, "g1f3", "Nf3", "Ng1-f3"},
+		{Notation{Style: Long}, "e2e4", "e4", "e2-e4"},
+		{Notation{Style: Long}, "f1b5", "Bxb5+", "Bf1xb5+"},
+		{Notation{Style: UCI}, "e7e8q", "e8=Q#", "e7e8q"},
+		{Notation{Style: SAN, Language: "de"}, "e7e8q", "e8=Q#", "e8=D#"},
+		{Notation{Style: SAN, Language: "de"}, "b1c3", "Nc3", "Sc3"},
+		{Notation{Style: Long, Language: "fr"}, "e1g1", "O-O", "O-O"},
+		{Notation{Style: SAN, Language: "fr"}, "e1e2", "Ke2", "Re2"},
+		{Notation{Style: Figurine}, "d7c8n", "dxc8=N+", "dxc8=♘+"},
+		{Default, "e2e5", "", "e2e5"},
+	}
+	for _, tt := range tests {
+		if got := tt.n.Format(tt.uci, tt.san); got != tt.want {
+			t.Errorf("%+v.Format(%q, %q) = %q, want %q", tt.n, tt.uci, tt.san, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if n, err := New("Figurine", "DE"); err != nil || n != (Notation{Style: Figurine, Language: "de"}) {
+		t.Errorf("Unexpected notation %+v (%v)", n, err)
+	}
+	if n, err := New("", ""); err != nil || n != Default {
+		t.Errorf("Expected the default notation, got %+v (%v)", n, err)
+	}
+	if _, err := New("klingon", ""); err == nil {
+		t.Error("Expected an error for an unknown style")
+	}
+	if _, err := New("san", "xx"); err == nil {
+		t.Error("Expected an error for an unknown language")
+	}
+}
+
+func TestParse(t *testing.T) {
+	const start = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	const italian = "r1bqk1nr/pppp1ppp/2n5/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"
+	tests := []struct {
+		n     Notation
+		fen   string
+		input string
+		want  string
+	}{
+		{Default, start, "Nf3", "g1f3"},
+		{Default, start, "e4", "e2e4"},
+		{Default, start, "g1f3", "g1f3"},
+		{Default, start, "Ng1-f3", "g1f3"},
+		{Default, start, "♘f3", "g1f3"},
+		{Default, start, "e2-e4", "e2e4"},
+		{Notation{Style: SAN, Language: "de"}, start, "Sf3", "g1f3"},
+		{Notation{Style: SAN, Language: "de"}, italian, "Lxf7+", "c4f7"},
+		{Notation{Style: SAN, Language: "fr"}, italian, "Re2", "e1e2"},
+		{Default, italian, "0-0", "e1g1"},
+		{Default, italian, "O-O", "e1g1"},
+		{Default, italian, "Bxf7+!", "c4f7"},
+		{Default, italian, "Ngg5", "f3g5"},
+	}
+	for _, tt := range tests {
+		got, err := tt.n.Parse(tt.fen, tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("%+v.Parse(%q) = %q, %v; want %q", tt.n, tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"Ke2", "e5", "", "Nd2"} {
+		if got, err := Default.Parse(start, input); err == nil {
+			t.Errorf("Expected an error for %q, got %q", input, got)
+		}
+	}
+}
+
+func TestParse_Ambiguous(t *testing.T) {
+	// Knights on b1 and f3 can both go to d2
+	const fen = "rnbqkbnr/pppppppp/8/8/8/5N2/PPP1PPPP/RNBQKB1R w KQkq - 0 1"
+	_, err := Default.Parse(fen, "Nd2")
+	amb, ok := err.(*AmbiguousError)
+	if !ok {
+		t.Fatalf("Expected an *AmbiguousError, got %v", err)
+	}
+	if strings.Join(amb.Moves, " ") != "Nbd2 Nfd2" {
+		t.Errorf("Unexpected moves %v", amb.Moves)
+	}
+	if got, err := Default.Parse(fen, "Nbd2"); err != nil || got != "b1d2" {
+		t.Errorf("Parse(Nbd2) = %q, %v", got, err)
+	}
+	if got, err := (Notation{Style: SAN, Language: "de"}).Parse(fen, "Sfd2"); err != nil || got != "f3d2" {
+		t.Errorf("Parse(Sfd2) = %q, %v", got, err)
+	}
+}
+
+func TestParse_Promotion(t *testing.T) {
+	const fen = "8/4P1k1/8/8/8/8/8/4K3 w - - 0 1"
+	for input, want := range map[string]string{"e8=N": "e7e8n", "e8N": "e7e8n", "e7e8n": "e7e8n", "e8=Q": "e7e8q"} {
+		if got, err := Default.Parse(fen, input); err != nil || got != want {
+			t.Errorf("Parse(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	const start = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	got := Default.Complete(start, "N")
+	sort.Strings(got)
+	if strings.Join(got, " ") != "Na3 Nc3 Nf3 Nh3" {
+		t.Errorf("Complete(N) = %v", got)
+	}
+	if got := Default.Complete(start, "e2"); strings.Join(got, " ") != "e3 e4" && strings.Join(got, " ") != "e4 e3" {
+		t.Errorf("Complete(e2) = %v", got)
+	}
+	if got := Default.Complete(start, "Q"); len(got) != 0 {
+		t.Errorf("Complete(Q) = %v, want none", got)
+	}
+}
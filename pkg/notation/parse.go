@@ -0,0 +1,202 @@
+package notation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corentings/chess"
+)
+
+// normalizer removes the characters that may be left out when typing a move.
+var normalizer = strings.NewReplacer("-", "", "x", "", ":", "", "=", "", "+", "", "#", "", "!", "", "?", "", " ", "")
+
+// normalize returns the move in the form used to compare typed moves.
+func normalize(move string) string {
+	move = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(move), "e.p."))
+	move = strings.ReplaceAll(move, "0", "O")
+	return normalizer.Replace(move)
+}
+
+// AmbiguousError is returned by Parse for a move that several legal moves match,
+// such as Nd2 with knights on b1 and f3.
+type AmbiguousError struct {
+	Input string
+	Moves []string // The matching moves, written in the notation
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("ambiguous move %q, could be %s", e.Input, strings.Join(e.Moves, ", "))
+}
+
+// legalMove is a legal move written in UCI and SAN.
+type legalMove struct {
+	uci, san string
+}
+
+// legalMoves returns the legal moves in the position given as FEN.
+func legalMoves(fen string) ([]legalMove, error) {
+	fenFunc, err := chess.FEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	pos := chess.NewGame(fenFunc).Position()
+	var moves []legalMove
+	for _, m := range pos.ValidMoves() {
+		moves = append(moves, legalMove{
+			uci: chess.UCINotation{}.Encode(pos, m),
+			san: chess.AlgebraicNotation{}.Encode(pos, m),
+		})
+	}
+	return moves, nil
+}
+
+// candidates returns the notations typed moves are compared with. The
+// notation itself is tried first, as its letters may mean other pieces in
+// English (the French R is the king).
+func (n Notation) candidates() []Notation {
+	return []Notation{
+		n,
+		{Style: SAN, Language: n.Language},
+		{Style: Long, Language: n.Language},
+		{Style: Figurine},
+		{Style: SAN, Language: "en"},
+		{Style: Long, Language: "en"},
+		{Style: UCI},
+	}
+}
+
+// matches reports whether the normalized form of a move equals, or with prefix
+// set starts with, the normalized typed move.
+func (n Notation) matches(m legalMove, typed string, prefix bool) bool {
+	form := normalize(n.Format(m.uci, m.san))
+	if n.Style == UCI {
+		form, typed = strings.ToLower(form), strings.ToLower(typed)
+	}
+	if prefix {
+		return strings.HasPrefix(form, typed)
+	}
+	return form == typed
+}
+
+// short returns a piece move without the square it starts from, e.g. Nd2 for
+// Nbd2, and an empty string for pawn moves and castling.
+func (n Notation) short(m legalMove) string {
+	if len(m.uci) < 4 || strings.IndexByte(pieceOrder, m.san[0]) < 0 {
+		return ""
+	}
+	return n.piece(m.san[0]) + m.uci[2:4]
+}
+
+// Parse reads a move typed in the position given as FEN and returns it in UCI.
+// Moves may be written in the notation itself or in any other style: SAN and
+// long algebraic notation with the notation's or English piece letters,
+// figurines and UCI. Checks, captures, dashes and annotations may be left out.
+// A piece move that lacks the disambiguation it needs gives an *AmbiguousError.
+func (n Notation) Parse(fen, input string) (string, error) {
+	moves, err := legalMoves(fen)
+	if err != nil {
+		return "", err
+	}
+	typed := normalize(input)
+	if typed == "" {
+		return "", fmt.Errorf("no move given")
+	}
+
+	for _, c := range n.candidates() {
+		var matches, loose []legalMove
+		for _, m := range moves {
+			switch {
+			case c.matches(m, typed, false):
+				matches = append(matches, m)
+			case c.Style != UCI && c.Style != Long && normalize(c.short(m)) == typed:
+				loose = append(loose, m)
+			}
+		}
+		if len(matches) == 1 {
+			return matches[0].uci, nil
+		}
+		if len(matches) > 1 {
+			return "", n.ambiguous(input, matches)
+		}
+		if len(loose) > 1 {
+			return "", n.ambiguous(input, loose)
+		}
+	}
+
+	// Over-specified moves such as Ngf3 are decoded after translating them to
+	// English letters
+	fenFunc, _ := chess.FEN(fen)
+	pos := chess.NewGame(fenFunc).Position()
+	if move, err := (chess.AlgebraicNotation{}).Decode(pos, n.toEnglish(strings.TrimSpace(input))); err == nil {
+		return chess.UCINotation{}.Encode(pos, move), nil
+	}
+	return "", fmt.Errorf("illegal or unknown move %q", input)
+}
+
+// ambiguous returns the error for a move that the given moves match.
+func (n Notation) ambiguous(input string, moves []legalMove) error {
+	err := &AmbiguousError{Input: input}
+	for _, m := range moves {
+		err.Moves = append(err.Moves, n.Format(m.uci, m.san))
+	}
+	return err
+}
+
+// Complete returns the legal moves, written in the notation, that start like
+// the move typed so far in any of the notations Parse accepts.
+func (n Notation) Complete(fen, input string) []string {
+	moves, err := legalMoves(fen)
+	if err != nil {
+		return nil
+	}
+	typed := normalize(input)
+	var completions []string
+	for _, m := range moves {
+		for _, c := range n.candidates() {
+			if c.matches(m, typed, true) || (c.Style != UCI && c.short(m) != "" && strings.HasPrefix(normalize(c.short(m)), typed)) {
+				completions = append(completions, n.Format(m.uci, m.san))
+				break
+			}
+		}
+	}
+	return completions
+}
+
+// FormatUCI writes a move given in UCI in the position given as FEN. Moves that
+// are not legal there are returned unchanged.
+func (n Notation) FormatUCI(fen, uci string) string {
+	moves, _ := legalMoves(fen)
+	for _, m := range moves {
+		if m.uci == uci {
+			return n.Format(m.uci, m.san)
+		}
+	}
+	return uci
+}
+
+// toEnglish replaces the notation's piece letters and figurines with English
+// piece letters.
+func (n Notation) toEnglish(move string) string {
+	letters := n.letters()
+	var sb strings.Builder
+	for i, c := range move {
+		switch {
+		case indexRune(figurines, c) >= 0:
+			sb.WriteByte(pieceOrder[indexRune(figurines, c)])
+		case (i == 0 || strings.HasSuffix(sb.String(), "=")) && indexRune(letters, c) >= 0:
+			sb.WriteByte(pieceOrder[indexRune(letters, c)])
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+func indexRune(runes []rune, r rune) int {
+	for i, c := range runes {
+		if c == r {
+			return i
+		}
+	}
+	return -1
+}
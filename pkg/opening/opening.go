@@ -0,0 +1,80 @@
+// Package opening classifies positions by the Encyclopaedia of Chess Openings.
+// The table is keyed by position rather than by move order, so transpositions
+// into a known opening are recognised.
+package opening
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+// eco.tsv is the lichess opening table (https://github.com/lichess-org/chess-openings)
+// with the columns eco, name, pgn, uci and epd.
+//
+//go:embed eco.tsv
+var ecoTable string
+
+// Opening is a named opening line of the ECO table.
+type Opening struct {
+	ECO  string // ECO code such as "C60"
+	Name string // Name such as "Ruy Lopez"
+	PGN  string // Moves of the line, e.g. "1. e4 e5 2. Nf3 Nc6 3. Bb5"
+}
+
+// String returns the ECO code followed by the name.
+func (o Opening) String() string {
+	return o.ECO + " " + o.Name
+}
+
+var (
+	loadOnce  sync.Once
+	positions map[string]Opening
+)
+
+// load parses the embedded table on first use.
+func load() {
+	lines := strings.Split(ecoTable, "\n")
+	positions = make(map[string]Opening, len(lines))
+	for _, line := range lines[1:] {
+		cols := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(cols) < 5 {
+			continue
+		}
+		key := positionKey(cols[4])
+		if _, ok := positions[key]; !ok {
+			positions[key] = Opening{ECO: cols[0], Name: cols[1], PGN: cols[2]}
+		}
+	}
+}
+
+// positionKey returns the piece placement, side to move and castling rights
+// of a FEN or EPD. The en passant square is left out because the table only
+// sets it when a capture is possible.
+func positionKey(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 3 {
+		fields = fields[:3]
+	}
+	return strings.Join(fields, " ")
+}
+
+// Lookup returns the opening of the position given as FEN.
+func Lookup(fen string) (Opening, bool) {
+	loadOnce.Do(load)
+	o, ok := positions[positionKey(fen)]
+	return o, ok
+}
+
+// Classify returns the opening of a game given by the FENs of its positions,
+// from the start position to the current one. It is the opening of the latest
+// position found in the table, so a game keeps its opening name after leaving
+// the known lines. ply is the index of that position in fens.
+func Classify(fens []string) (o Opening, ply int, ok bool) {
+	for i := len(fens) - 1; i >= 0; i-- {
+		if o, ok := Lookup(fens[i]); ok {
+			return o, i, true
+		}
+	}
+	return Opening{}, -1, false
+}
@@ -0,0 +1,58 @@
+package opening
+
+import (
+	"testing"
+
+	"github.com/corentings/chess"
+)
+
+// playFENs plays SAN moves from the start position and returns the FENs of
+// all positions including the start.
+func playFENs(t *testing.T, moves ...string) []string {
+	t.Helper()
+	game := chess.NewGame()
+	fens := []string{game.FEN()}
+	for _, m := range moves {
+		if err := game.MoveStr(m); err != nil {
+			t.Fatalf("Invalid move %s: %v", m, err)
+		}
+		fens = append(fens, game.FEN())
+	}
+	return fens
+}
+
+func TestLookup_RuyLopez(t *testing.T) {
+	fens := playFENs(t, "e4", "e5", "Nf3", "Nc6", "Bb5")
+	o, ok := Lookup(fens[len(fens)-1])
+	if !ok || o.ECO != "C60" || o.Name != "Ruy Lopez" {
+		t.Errorf("Expected C60 Ruy Lopez, got %v (found %v)", o, ok)
+	}
+	// The en passant square of 1. e4 does not prevent a match
+	if o, ok := Lookup(fens[1]); !ok || o.ECO != "B00" {
+		t.Errorf("Expected B00 after 1. e4, got %v (found %v)", o, ok)
+	}
+	if _, ok := Lookup(fens[0]); ok {
+		t.Error("Expected the start position to have no opening")
+	}
+}
+
+func TestClassify_Transposition(t *testing.T) {
+	direct := playFENs(t, "d4", "Nf6", "c4", "e6", "Nc3", "Bb4")
+	transposed := playFENs(t, "c4", "e6", "Nc3", "Nf6", "d4", "Bb4")
+	a, _, okA := Classify(direct)
+	b, _, okB := Classify(transposed)
+	if !okA || !okB || a != b || a.ECO[0] != 'E' || a.Name != "Nimzo-Indian Defense" {
+		t.Errorf("Expected the same Nimzo-Indian for both move orders, got %v and %v", a, b)
+	}
+}
+
+func TestClassify_KeepsLastKnownOpening(t *testing.T) {
+	fens := playFENs(t, "e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6", "O-O", "Be7", "Re1", "b5", "Bb3", "d6", "c3", "O-O", "h3", "Na5", "Bc2", "c5", "d4", "Qc7", "a4")
+	o, ply, ok := Classify(fens)
+	if !ok || o.ECO[0] != 'C' || ply >= len(fens)-1 {
+		t.Errorf("Expected a Ruy Lopez from an earlier ply, got %v at ply %d", o, ply)
+	}
+	if _, _, ok := Classify(fens[:1]); ok {
+		t.Error("Expected no opening for the start position only")
+	}
+}
@@ -0,0 +1,185 @@
+package pgn
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Annotation holds the comment, NAGs and embedded commands attached to a move.
+type Annotation struct {
+	NAGs    []int    // Numeric annotation glyphs, e.g. 1 for "!"
+	Comment string   // Comment text without the recognised commands
+	Eval    string   // [%eval] engine evaluation, e.g. "0.35" or "#-3"
+	Clock   string   // [%clk] remaining clock time, e.g. "1:05:12"
+	Arrows  []string // [%cal] coloured arrows, e.g. "Ge2e4"
+	Squares []string // [%csl] coloured squares, e.g. "Rd4"
+}
+
+// Move is a main line move together with its annotation.
+type Move struct {
+	SAN string
+	Annotation
+}
+
+// IsEmpty reports whether the annotation carries no information.
+func (a Annotation) IsEmpty() bool {
+	return len(a.NAGs) == 0 && a.Comment == "" && a.Eval == "" && a.Clock == "" &&
+		len(a.Arrows) == 0 && len(a.Squares) == 0
+}
+
+// Glyphs returns the NAGs as symbols. Move assessments such as "!?" come first
+// and are not separated, so they can be appended directly to the SAN.
+func (a Annotation) Glyphs() string {
+	var move, rest []string
+	for _, nag := range a.NAGs {
+		if nag >= 1 && nag <= 6 {
+			move = append(move, NAGGlyph(nag))
+		} else {
+			rest = append(rest, NAGGlyph(nag))
+		}
+	}
+	glyphs := strings.Join(move, "")
+	if len(rest) > 0 {
+		glyphs += " " + strings.Join(rest, " ")
+	}
+	return glyphs
+}
+
+// Movetext returns the annotation in PGN form, starting with a space, e.g.
+// ` $1 {[%eval 0.35] Best move}`, or an empty string if there is nothing to write.
+func (a Annotation) Movetext() string {
+	var sb strings.Builder
+	for _, nag := range a.NAGs {
+		fmt.Fprintf(&sb, " $%d", nag)
+	}
+	var parts []string
+	if a.Eval != "" {
+		parts = append(parts, "[%eval "+a.Eval+"]")
+	}
+	if a.Clock != "" {
+		parts = append(parts, "[%clk "+a.Clock+"]")
+	}
+	if len(a.Arrows) > 0 {
+		parts = append(parts, "[%cal "+strings.Join(a.Arrows, ",")+"]")
+	}
+	if len(a.Squares) > 0 {
+		parts = append(parts, "[%csl "+strings.Join(a.Squares, ",")+"]")
+	}
+	if a.Comment != "" {
+		// A closing brace would end the comment early
+		parts = append(parts, strings.ReplaceAll(a.Comment, "}", ")"))
+	}
+	if len(parts) > 0 {
+		sb.WriteString(" {" + strings.Join(parts, " ") + "}")
+	}
+	return sb.String()
+}
+
+var nagGlyphs = map[int]string{
+	1: "!", 2: "?", 3: "!!", 4: "??", 5: "!?", 6: "?!",
+	7: "□", 10: "=", 13: "∞", 14: "⩲", 15: "⩱", 16: "±", 17: "∓", 18: "+-", 19: "-+",
+	22: "⨀", 23: "⨀", 32: "⟳", 33: "⟳", 36: "↑", 37: "↑", 40: "→", 41: "→",
+	132: "⇆", 133: "⇆", 138: "⊕", 139: "⊕", 140: "∆", 142: "⌓", 145: "RR", 146: "N",
+}
+
+// suffixNAGs maps move suffixes to their NAGs.
+var suffixNAGs = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+// NAGGlyph returns the symbol of a NAG, or "$n" if it has none.
+func NAGGlyph(nag int) string {
+	if glyph, ok := nagGlyphs[nag]; ok {
+		return glyph
+	}
+	return fmt.Sprintf("$%d", nag)
+}
+
+var commandRegex = regexp.MustCompile(`\[%(\w+)\s+([^\]]*)\]`)
+
+// parseComment adds the text and commands of a {} comment to the annotation.
+func (a *Annotation) parseComment(comment string) {
+	comment = strings.TrimSuffix(strings.TrimPrefix(comment, "{"), "}")
+	text := commandRegex.ReplaceAllStringFunc(comment, func(cmd string) string {
+		m := commandRegex.FindStringSubmatch(cmd)
+		value := strings.TrimSpace(m[2])
+		switch m[1] {
+		case "eval":
+			a.Eval = value
+		case "clk":
+			a.Clock = value
+		case "cal":
+			a.Arrows = append(a.Arrows, splitList(value)...)
+		case "csl":
+			a.Squares = append(a.Squares, splitList(value)...)
+		default:
+			// Unknown commands stay in the comment
+			return cmd
+		}
+		return ""
+	})
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return
+	}
+	if a.Comment != "" {
+		a.Comment += " "
+	}
+	a.Comment += text
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseMainline returns the main line moves of a movetext with their
+// annotations. Comments before the first move are returned as intro.
+// Variations are skipped.
+func ParseMainline(movetext string) (intro string, moves []Move) {
+	var introAnnotation Annotation
+	depth := 0 // Variation nesting depth
+	for _, tok := range tokenize(movetext) {
+		current := &introAnnotation
+		if len(moves) > 0 {
+			current = &moves[len(moves)-1].Annotation
+		}
+		switch {
+		case tok == "(":
+			depth++
+		case tok == ")":
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0, resultTokens[tok], moveNumberRegex.MatchString(tok):
+			// Not part of the main line
+		case strings.HasPrefix(tok, "{"):
+			current.parseComment(tok)
+		case strings.HasPrefix(tok, "$"):
+			if nag, err := strconv.Atoi(tok[1:]); err == nil {
+				current.NAGs = append(current.NAGs, nag)
+			}
+		default:
+			san := strings.TrimRight(tok, "!?")
+			nag, ok := suffixNAGs[tok[len(san):]]
+			if san == "" {
+				// A glyph separated from its move
+				if ok {
+					current.NAGs = append(current.NAGs, nag)
+				}
+				continue
+			}
+			move := Move{SAN: san}
+			if ok {
+				move.NAGs = []int{nag}
+			}
+			moves = append(moves, move)
+		}
+	}
+	return introAnnotation.Comment, moves
+}
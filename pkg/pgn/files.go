@@ -0,0 +1,28 @@
+package pgn
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WalkFiles calls fn for the PGN file at path, or for every .pgn file below
+// path if it is a directory. Walking stops at the first error.
+func WalkFiles(path string, fn func(path string, r io.Reader) error) error {
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (p != path && !strings.EqualFold(filepath.Ext(p), ".pgn")) {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return fn(p, f)
+	})
+}
@@ -0,0 +1,44 @@
+package pgn
+
+import "strings"
+
+// filterFields are the tags searched by terms without a field prefix.
+var filterFields = []string{"White", "Black", "Event", "Site", "Date", "Result", "ECO"}
+
+// Filter returns the games matching every term of the query. Terms are separated
+// by spaces and match case-insensitively as substrings of the White, Black, Event,
+// Site, Date, Result or ECO tag. A term of the form tag:value only searches the
+// named tag, e.g. "white:carlsen eco:B90".
+func Filter(games []GameInfo, query string) []GameInfo {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return games
+	}
+	var matches []GameInfo
+	for _, g := range games {
+		if matchesAll(g, terms) {
+			matches = append(matches, g)
+		}
+	}
+	return matches
+}
+
+func matchesAll(g GameInfo, terms []string) bool {
+	for _, term := range terms {
+		fields := filterFields
+		if name, value, ok := strings.Cut(term, ":"); ok && name != "" {
+			fields, term = []string{name}, value
+		}
+		found := false
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(g.Tag(field)), term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+// Package pgn splits PGN files into games and reads their tag pairs, so that
+// databases with many games can be listed without decoding every game.
+package pgn
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Tag is a PGN tag pair such as [White "Carlsen, Magnus"].
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// GameInfo describes one game of a PGN file.
+type GameInfo struct {
+	Index int    // Position of the game in the file, starting at 0
+	Tags  []Tag  // Tag pairs in file order
+	Moves int    // Number of plies in the main line
+	Raw   string // The complete PGN text of the game
+}
+
+// Tag returns the value of the named tag, or an empty string if it is missing.
+func (gi GameInfo) Tag(name string) string {
+	return TagValue(gi.Tags, name)
+}
+
+var tagRegex = regexp.MustCompile(`^\[\s*([A-Za-z0-9_]+)\s+"((?:[^"\\]|\\.)*)"\s*\]`)
+
+// Split reads all games from r and returns their raw PGN texts.
+func Split(r io.Reader) ([]string, error) {
+	var games []string
+	var sb strings.Builder
+	inMoves := false  // Movetext of the current game has started
+	commentDepth := 0 // Inside a {} comment, which may span lines
+
+	flush := func() {
+		if text := strings.TrimSpace(sb.String()); text != "" {
+			games = append(games, text)
+		}
+		sb.Reset()
+		inMoves = false
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if commentDepth == 0 && strings.HasPrefix(line, "[") {
+			// A tag pair after movetext starts the next game
+			if inMoves {
+				flush()
+			}
+		} else if commentDepth == 0 && line != "" && !strings.HasPrefix(line, "%") {
+			inMoves = true
+		}
+		commentDepth += strings.Count(line, "{") - strings.Count(line, "}")
+		if commentDepth < 0 {
+			commentDepth = 0
+		}
+		sb.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return games, nil
+}
+
+// ParseTags returns the tag pairs at the start of a raw PGN game.
+func ParseTags(raw string) []Tag {
+	var tags []Tag
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		m := tagRegex.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+		value := strings.ReplaceAll(m[2], `\"`, `"`)
+		value = strings.ReplaceAll(value, `\\`, `\`)
+		tags = append(tags, Tag{Name: m[1], Value: value})
+	}
+	return tags
+}
+
+// Movetext returns the part of a raw PGN game after the tag pairs.
+func Movetext(raw string) string {
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && !tagRegex.MatchString(line) {
+			return strings.Join(lines[i:], "\n")
+		}
+	}
+	return ""
+}
+
+// ReadGames reads all games from r together with their tags and move counts.
+func ReadGames(r io.Reader) ([]GameInfo, error) {
+	raws, err := Split(r)
+	if err != nil {
+		return nil, err
+	}
+	games := make([]GameInfo, len(raws))
+	for i, raw := range raws {
+		games[i] = GameInfo{
+			Index: i,
+			Tags:  ParseTags(raw),
+			Moves: len(MainlineSAN(Movetext(raw))),
+			Raw:   raw,
+		}
+	}
+	return games, nil
+}
+
+var (
+	moveNumberRegex = regexp.MustCompile(`^\d+\.+$`)
+	resultTokens    = map[string]bool{"1-0": true, "0-1": true, "1/2-1/2": true, "*": true}
+)
+
+// MainlineSAN returns the main line moves of a movetext, without move numbers,
+// comments, variations, NAGs and the result.
+func MainlineSAN(movetext string) []string {
+	var moves []string
+	depth := 0 // Variation nesting depth
+	for _, tok := range tokenize(movetext) {
+		switch {
+		case tok == "(":
+			depth++
+		case tok == ")":
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0, strings.HasPrefix(tok, "{"), strings.HasPrefix(tok, "$"), resultTokens[tok], moveNumberRegex.MatchString(tok):
+			// Not a main line move
+		default:
+			moves = append(moves, tok)
+		}
+	}
+	return moves
+}
+
+// tokenize splits movetext into tokens. Comments are returned as one token
+// including their braces, and move numbers are split from attached moves.
+func tokenize(movetext string) []string {
+	var tokens []string
+	var cur strings.Builder
+	emit := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	runes := []rune(movetext)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '{':
+			emit()
+			end := i + 1
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			tokens = append(tokens, string(runes[i:min(end+1, len(runes))]))
+			i = end
+		case c == ';':
+			// Rest of line comment
+			emit()
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case c == '(' || c == ')':
+			emit()
+			tokens = append(tokens, string(c))
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			emit()
+		case c == '.':
+			cur.WriteRune(c)
+			// Split "12.e4" into "12." and "e4"
+			if i+1 < len(runes) && runes[i+1] != '.' && moveNumberRegex.MatchString(cur.String()) {
+				emit()
+			}
+		default:
+			cur.WriteRune(c)
+		}
+	}
+	emit()
+	return tokens
+}
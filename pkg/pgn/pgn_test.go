@@ -0,0 +1,160 @@
+package pgn
+
+import (
+	"strings"
+	"testing"
+)
+
+const twoGames = `[Event "Club Championship"]
+[Site "Berlin"]
+[Date "2024.05.01"]
+[White "Alice"]
+[Black "Bob"]
+[Result "1-0"]
+[ECO "C20"]
+
+1. e4 e5 2. Qh5 {Aggressive
+[but premature]} Nc6 (2... g6 3. Qf3) 3. Bc4 Nf6?? 4. Qxf7# 1-0
+
+[Event "Club Championship"]
+[White "Carol \"CJ\" Jones"]
+[Black "Alice"]
+[Result "1/2-1/2"]
+
+1.d4 d5 2.c4 $1 e6 1/2-1/2
+`
+
+func TestSplit_MultipleGames(t *testing.T) {
+	games, err := Split(strings.NewReader(twoGames))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("Expected 2 games, got %d", len(games))
+	}
+	if !strings.Contains(games[0], "[but premature]") {
+		t.Error("Expected multi-line comment to stay in the first game")
+	}
+}
+
+func TestReadGames_TagsAndMoves(t *testing.T) {
+	games, err := ReadGames(strings.NewReader(twoGames))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if games[0].Tag("white") != "Alice" || games[0].Tag("ECO") != "C20" {
+		t.Errorf("Unexpected tags: %v", games[0].Tags)
+	}
+	if games[0].Moves != 7 {
+		t.Errorf("Expected 7 plies in the first game, got %d", games[0].Moves)
+	}
+	if games[1].Tag("White") != `Carol "CJ" Jones` {
+		t.Errorf("Expected escaped quotes to be unescaped, got %q", games[1].Tag("White"))
+	}
+	if games[1].Moves != 4 || games[1].Index != 1 {
+		t.Errorf("Expected 4 plies at index 1, got %d at %d", games[1].Moves, games[1].Index)
+	}
+}
+
+func TestMainlineSAN_SkipsAnnotations(t *testing.T) {
+	got := MainlineSAN("1. e4 {best by test} e5 (1... c5 2. Nf3) 2. Nf3 $1 Nc6 ; comment\n3. Bb5 *")
+	want := []string{"e4", "e5", "Nf3", "Nc6", "Bb5"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	games, _ := ReadGames(strings.NewReader(twoGames))
+	if got := Filter(games, "alice"); len(got) != 2 {
+		t.Errorf("Expected 2 games with Alice, got %d", len(got))
+	}
+	if got := Filter(games, "white:alice"); len(got) != 1 || got[0].Index != 0 {
+		t.Errorf("Expected only the first game for white:alice, got %v", got)
+	}
+	if got := Filter(games, "alice 1/2"); len(got) != 1 || got[0].Index != 1 {
+		t.Errorf("Expected only the drawn game, got %v", got)
+	}
+	if got := Filter(games, ""); len(got) != 2 {
+		t.Errorf("Expected empty query to match all games, got %d", len(got))
+	}
+}
+
+func TestWriteTags_RoundTrip(t *testing.T) {
+	tags := []Tag{
+		{Name: "Event", Value: "Club Championship"},
+		{Name: "White", Value: `Carol "CJ" Jones`},
+		{Name: "Annotator", Value: `C:\chess`},
+	}
+	var sb strings.Builder
+	if err := WriteTags(&sb, tags); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got := ParseTags(sb.String() + "\n1. e4 *")
+	if len(got) != len(tags) {
+		t.Fatalf("Expected %d tags, got %d", len(tags), len(got))
+	}
+	for i := range tags {
+		if got[i] != tags[i] {
+			t.Errorf("Tag %d: expected %v, got %v", i, tags[i], got[i])
+		}
+	}
+}
+
+func TestParseTagLines(t *testing.T) {
+	tags, err := ParseTagLines("Event: Casual Game\n\nWhite: Alice\nTimeControl: 300+2\nWhite: Bob\n")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tags) != 3 {
+		t.Fatalf("Expected 3 tags, got %d", len(tags))
+	}
+	if TagValue(tags, "White") != "Bob" {
+		t.Errorf("Expected White to be Bob, got %q", TagValue(tags, "White"))
+	}
+	if TagValue(tags, "TimeControl") != "300+2" {
+		t.Errorf("Expected TimeControl to be 300+2, got %q", TagValue(tags, "TimeControl"))
+	}
+	if _, err := ParseTagLines("not a tag"); err == nil {
+		t.Error("Expected error for line without colon")
+	}
+}
+
+func TestParseMainline_Annotations(t *testing.T) {
+	movetext := `{Intro} 1. e4 $1 {[%eval 0.3] [%clk 0:05:00] Best by test} e5 2. Qh5?! {[%cal Gd1h5,Rh5f7]} Nc6 (2... g6) 3. Bc4 Nf6?? {[%csl Rf7] Forgets the threat} 4. Qxf7# 1-0`
+	intro, moves := ParseMainline(movetext)
+	if intro != "Intro" {
+		t.Errorf("Expected intro comment, got %q", intro)
+	}
+	if len(moves) != 7 {
+		t.Fatalf("Expected 7 moves, got %d", len(moves))
+	}
+	e4 := moves[0]
+	if e4.SAN != "e4" || e4.Eval != "0.3" || e4.Clock != "0:05:00" || e4.Comment != "Best by test" || e4.Glyphs() != "!" {
+		t.Errorf("Unexpected annotation for e4: %+v", e4)
+	}
+	if qh5 := moves[2]; qh5.SAN != "Qh5" || qh5.Glyphs() != "?!" || len(qh5.Arrows) != 2 {
+		t.Errorf("Unexpected annotation for Qh5: %+v", qh5)
+	}
+	if nf6 := moves[5]; nf6.SAN != "Nf6" || nf6.Glyphs() != "??" || nf6.Squares[0] != "Rf7" {
+		t.Errorf("Unexpected annotation for Nf6: %+v", nf6)
+	}
+	if !moves[6].IsEmpty() {
+		t.Errorf("Expected no annotation for the last move, got %+v", moves[6].Annotation)
+	}
+}
+
+func TestAnnotationMovetext_RoundTrip(t *testing.T) {
+	a := Annotation{NAGs: []int{5, 16}, Comment: "Sharp", Eval: "1.20", Clock: "0:59:58", Arrows: []string{"Ge2e4"}}
+	_, moves := ParseMainline("1. e4" + a.Movetext())
+	if len(moves) != 1 {
+		t.Fatalf("Expected 1 move, got %d", len(moves))
+	}
+	got := moves[0].Annotation
+	if got.Movetext() != a.Movetext() {
+		t.Errorf("Expected %q, got %q", a.Movetext(), got.Movetext())
+	}
+	if got.Glyphs() != "!? ±" {
+		t.Errorf("Expected glyphs \"!? ±\", got %q", got.Glyphs())
+	}
+}
@@ -0,0 +1,85 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// SevenTagRoster lists the tags every PGN game should have, in export order.
+var SevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+var tagNameRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// TagValue returns the value of the named tag, or an empty string if it is missing.
+func TagValue(tags []Tag, name string) string {
+	for _, t := range tags {
+		if strings.EqualFold(t.Name, name) {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// SetTag sets the value of the named tag, appending it if it is missing.
+func SetTag(tags []Tag, name, value string) []Tag {
+	for i, t := range tags {
+		if strings.EqualFold(t.Name, name) {
+			tags[i].Value = value
+			return tags
+		}
+	}
+	return append(tags, Tag{Name: name, Value: value})
+}
+
+// RemoveTag returns tags without the named tag.
+func RemoveTag(tags []Tag, name string) []Tag {
+	var kept []Tag
+	for _, t := range tags {
+		if !strings.EqualFold(t.Name, name) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// WriteTags writes tags as PGN tag pair lines in the given order.
+func WriteTags(w io.Writer, tags []Tag) error {
+	for _, t := range tags {
+		value := strings.ReplaceAll(t.Value, `\`, `\\`)
+		value = strings.ReplaceAll(value, `"`, `\"`)
+		if _, err := fmt.Fprintf(w, "[%s \"%s\"]\n", t.Name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatTagLines returns tags as editable "Name: value" lines.
+func FormatTagLines(tags []Tag) string {
+	var sb strings.Builder
+	for _, t := range tags {
+		fmt.Fprintf(&sb, "%s: %s\n", t.Name, t.Value)
+	}
+	return sb.String()
+}
+
+// ParseTagLines parses "Name: value" lines as written by FormatTagLines. Empty
+// lines are skipped, and a tag given more than once keeps its last value.
+func ParseTagLines(text string) ([]Tag, error) {
+	var tags []Tag
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !tagNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("line %d: expected \"Name: value\"", i+1)
+		}
+		tags = SetTag(tags, name, strings.TrimSpace(value))
+	}
+	return tags, nil
+}
@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/RubikNube/TerminalChess/pkg/text"
+)
+
+// WriteText writes the report as text tables. At most maxOpenings openings are
+// listed, all if maxOpenings is 0.
+func WriteText(w io.Writer, r Report, maxOpenings int) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Games: %d, against the engine: %d (%d unfinished)\n", r.Games, r.EngineGames, r.Unfinished)
+	fmt.Fprintf(bw, "Average length: %.1f moves\n", r.AverageLength)
+
+	fmt.Fprintf(bw, "\nResults against the engine\n")
+	fmt.Fprintf(bw, "%-20s %-22s %-22s %s\n", "Level", "As White", "As Black", "Total")
+	for _, l := range r.Levels {
+		fmt.Fprintf(bw, "%-20s %-22s %-22s %s\n", l.Label, formatRecord(l.White), formatRecord(l.Black), formatRecord(l.Total()))
+	}
+
+	fmt.Fprintf(bw, "\nMost played openings\n")
+	fmt.Fprintf(bw, "%-4s %-44s %5s  %s\n", "ECO", "Opening", "Games", "Results")
+	for i, o := range r.Openings {
+		if maxOpenings > 0 && i == maxOpenings {
+			break
+		}
+		fmt.Fprintf(bw, "%-4s %-44s %5d  %s\n", o.ECO, text.Truncate(o.Name, 44), o.Count, formatRecord(o.Record))
+	}
+
+	fmt.Fprintf(bw, "\nPerformance over time\n")
+	fmt.Fprintf(bw, "%-8s %-22s %8s %11s\n", "Month", "Results", "Avg Elo", "Performance")
+	for _, p := range r.Periods {
+		fmt.Fprintf(bw, "%-8s %-22s %8d %11d\n", p.Period, formatRecord(p.Record), p.AverageElo, p.Performance)
+	}
+	return bw.Flush()
+}
+
+// formatRecord returns e.g. "+3 =1 -2 (58.3%)".
+func formatRecord(r Record) string {
+	if r.Games() == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("+%d =%d -%d (%.1f%%)", r.Wins, r.Draws, r.Losses, r.Percent())
+}
+
+// WriteCSV writes the report as one CSV table. The section column tells the
+// tables apart: "summary", "level", "opening" and "period".
+func WriteCSV(w io.Writer, r Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"section", "label", "detail", "games", "wins", "draws", "losses", "score", "elo", "performance"})
+	row := func(section, label, detail string, games int, rec Record, elo, performance string) {
+		cw.Write([]string{section, label, detail, strconv.Itoa(games),
+			strconv.Itoa(rec.Wins), strconv.Itoa(rec.Draws), strconv.Itoa(rec.Losses),
+			strconv.FormatFloat(rec.Percent(), 'f', 1, 64), elo, performance})
+	}
+	// Summary values are given in the detail column
+	summary := func(label, value string) {
+		cw.Write([]string{"summary", label, value, "", "", "", "", "", "", ""})
+	}
+	summary("games", strconv.Itoa(r.Games))
+	summary("engine games", strconv.Itoa(r.EngineGames))
+	summary("unfinished", strconv.Itoa(r.Unfinished))
+	summary("average length", strconv.FormatFloat(r.AverageLength, 'f', 1, 64))
+	for _, l := range r.Levels {
+		elo := strconv.Itoa(l.Elo)
+		row("level", l.Label, "white", l.White.Games(), l.White, elo, "")
+		row("level", l.Label, "black", l.Black.Games(), l.Black, elo, "")
+		row("level", l.Label, "total", l.Total().Games(), l.Total(), elo, "")
+	}
+	for _, o := range r.Openings {
+		row("opening", o.ECO, o.Name, o.Count, o.Record, "", "")
+	}
+	for _, p := range r.Periods {
+		row("period", p.Period, "", p.Games(), p.Record, strconv.Itoa(p.AverageElo), strconv.Itoa(p.Performance))
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,303 @@
+// Package stats summarises games played against the engine: results by engine
+// strength and colour, game length, openings and performance over time.
+package stats
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RubikNube/TerminalChess/pkg/opening"
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+	"github.com/corentings/chess"
+)
+
+// engineRegex matches the engine's player tag as written by saved games, e.g.
+// "stockfish (Club, Elo: 1700)", "stockfish (Elo: 2000)" or, for levels that
+// don't limit the strength to their Elo, "stockfish (Maximum)". Tags of the
+// last form are only taken for the engine with a WhiteType or BlackType tag of
+// "program", since players such as "Kasparov, Garry (RUS)" look the same.
+var engineRegex = regexp.MustCompile(`^(.+?) \((?:([^,():]+)|(?:([^,():]+), )?Elo: (\d+))\)$`)
+
+// Game is a saved game seen from the player's side.
+type Game struct {
+	Date      string // Date tag, e.g. "2024.05.01"
+	Engine    string // Engine name, empty if neither side is the engine
+	Level     string // Difficulty level such as "Club", if known
+	EngineElo int    // Engine Elo, 0 for unlimited strength
+	Color     string // Colour of the player, "white" or "black"
+	Result    string // Result tag
+	Plies     int    // Number of half moves
+	ECO       string
+	Opening   string
+}
+
+// Score returns the player's points: 1 for a win, 0.5 for a draw and 0 for a
+// loss. ok is false for unfinished games.
+func (g Game) Score() (score float64, ok bool) {
+	switch g.Result {
+	case "1/2-1/2":
+		return 0.5, true
+	case "1-0":
+		if g.Color == "white" {
+			return 1, true
+		}
+		return 0, true
+	case "0-1":
+		if g.Color == "black" {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// FromPGN reads the tags and moves of a game. Games without ECO tags are
+// classified by their moves.
+func FromPGN(info pgn.GameInfo) Game {
+	g := Game{
+		Date:    info.Tag("Date"),
+		Result:  info.Tag("Result"),
+		Plies:   info.Moves,
+		ECO:     info.Tag("ECO"),
+		Opening: info.Tag("Opening"),
+		Color:   "white",
+	}
+	for _, side := range []string{"White", "Black"} {
+		m := engineRegex.FindStringSubmatch(info.Tag(side))
+		if m == nil || (m[2] != "" && info.Tag(side+"Type") != "program") {
+			continue
+		}
+		g.Engine, g.Level = m[1], m[2]+m[3]
+		g.EngineElo, _ = strconv.Atoi(m[4])
+		if side == "White" {
+			g.Color = "black"
+		}
+		break
+	}
+	if g.ECO == "" {
+		if o, ok := classify(info.Raw); ok {
+			g.ECO, g.Opening = o.ECO, o.Name
+		}
+	}
+	return g
+}
+
+// classify replays a game and returns its opening.
+func classify(raw string) (opening.Opening, bool) {
+	gameFunc, err := chess.PGN(strings.NewReader(raw))
+	if err != nil {
+		return opening.Opening{}, false
+	}
+	var fens []string
+	for _, pos := range chess.NewGame(gameFunc).Positions() {
+		fens = append(fens, pos.String())
+	}
+	o, _, ok := opening.Classify(fens)
+	return o, ok
+}
+
+// Record counts the results of finished games.
+type Record struct {
+	Wins, Draws, Losses int
+}
+
+// Games returns the number of finished games.
+func (r Record) Games() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+// Points returns the player's points.
+func (r Record) Points() float64 {
+	return float64(r.Wins) + float64(r.Draws)/2
+}
+
+// Percent returns the points as a percentage of the games.
+func (r Record) Percent() float64 {
+	if r.Games() == 0 {
+		return 0
+	}
+	return 100 * r.Points() / float64(r.Games())
+}
+
+func (r *Record) add(g Game) {
+	switch score, ok := g.Score(); {
+	case !ok:
+	case score == 1:
+		r.Wins++
+	case score == 0:
+		r.Losses++
+	default:
+		r.Draws++
+	}
+}
+
+// LevelRow holds the results against one engine strength.
+type LevelRow struct {
+	Label string // e.g. "Club (1700)"
+	Elo   int
+	White Record // Games with the player as White
+	Black Record
+}
+
+// Total returns the results with both colours.
+func (l LevelRow) Total() Record {
+	return Record{l.White.Wins + l.Black.Wins, l.White.Draws + l.Black.Draws, l.White.Losses + l.Black.Losses}
+}
+
+// OpeningRow holds the results of one opening.
+type OpeningRow struct {
+	ECO, Name string
+	Count     int // Games including unfinished ones
+	Record
+}
+
+// PeriodRow holds the results of one month against engines of known strength.
+type PeriodRow struct {
+	Period      string // "2024-05"
+	AverageElo  int    // Average engine Elo
+	Performance int    // Performance rating
+	Record
+}
+
+// Report is the summary of a set of games.
+type Report struct {
+	Games         int     // All games
+	EngineGames   int     // Games against the engine
+	Unfinished    int     // Engine games without a result
+	AverageLength float64 // Average length in moves
+	Levels        []LevelRow
+	Openings      []OpeningRow // Most played first
+	Periods       []PeriodRow  // Oldest first
+}
+
+// Build summarises the games.
+func Build(games []Game) Report {
+	r := Report{Games: len(games)}
+	levels := map[string]*LevelRow{}
+	openings := map[string]*OpeningRow{}
+	type period struct {
+		Record
+		eloSum int
+	}
+	periods := map[string]*period{}
+	plies := 0
+	for _, g := range games {
+		plies += g.Plies
+		if g.ECO != "" {
+			o := openings[g.ECO+g.Opening]
+			if o == nil {
+				o = &OpeningRow{ECO: g.ECO, Name: g.Opening}
+				openings[g.ECO+g.Opening] = o
+			}
+			o.Count++
+			if g.Engine != "" {
+				o.add(g)
+			}
+		}
+		if g.Engine == "" {
+			continue
+		}
+		r.EngineGames++
+		if _, ok := g.Score(); !ok {
+			r.Unfinished++
+			continue
+		}
+		label := levelLabel(g)
+		l := levels[label]
+		if l == nil {
+			l = &LevelRow{Label: label, Elo: g.EngineElo}
+			levels[label] = l
+		}
+		if g.Color == "white" {
+			l.White.add(g)
+		} else {
+			l.Black.add(g)
+		}
+		// Engines without an Elo and undated games have no place in the rating history
+		month, ok := monthOf(g.Date)
+		if g.EngineElo == 0 || !ok {
+			continue
+		}
+		p := periods[month]
+		if p == nil {
+			p = &period{}
+			periods[month] = p
+		}
+		p.add(g)
+		p.eloSum += g.EngineElo
+	}
+	if len(games) > 0 {
+		r.AverageLength = float64(plies) / float64(len(games)) / 2
+	}
+
+	for _, l := range levels {
+		r.Levels = append(r.Levels, *l)
+	}
+	sort.Slice(r.Levels, func(i, j int) bool {
+		if r.Levels[i].Elo != r.Levels[j].Elo {
+			// Levels without an Elo come after the rated ones
+			return r.Levels[j].Elo == 0 || (r.Levels[i].Elo != 0 && r.Levels[i].Elo < r.Levels[j].Elo)
+		}
+		return r.Levels[i].Label < r.Levels[j].Label
+	})
+	for _, o := range openings {
+		r.Openings = append(r.Openings, *o)
+	}
+	sort.Slice(r.Openings, func(i, j int) bool {
+		if r.Openings[i].Count != r.Openings[j].Count {
+			return r.Openings[i].Count > r.Openings[j].Count
+		}
+		return r.Openings[i].ECO+r.Openings[i].Name < r.Openings[j].ECO+r.Openings[j].Name
+	})
+	for month, p := range periods {
+		avg := p.eloSum / p.Games()
+		r.Periods = append(r.Periods, PeriodRow{
+			Period:      month,
+			AverageElo:  avg,
+			Performance: Performance(avg, p.Record),
+			Record:      p.Record,
+		})
+	}
+	sort.Slice(r.Periods, func(i, j int) bool { return r.Periods[i].Period < r.Periods[j].Period })
+	return r
+}
+
+// Performance returns the performance rating of a record against opponents of
+// the given average Elo: the average plus 400 times the wins minus the losses,
+// divided by the games.
+func Performance(averageElo int, r Record) int {
+	if r.Games() == 0 {
+		return 0
+	}
+	return averageElo + 400*(r.Wins-r.Losses)/r.Games()
+}
+
+// levelLabel names the engine strength of a game, e.g. "Club (1700)".
+func levelLabel(g Game) string {
+	switch {
+	case g.Level != "" && g.EngineElo > 0:
+		return g.Level + " (" + strconv.Itoa(g.EngineElo) + ")"
+	case g.Level != "":
+		return g.Level
+	case g.EngineElo > 0:
+		return "Elo " + strconv.Itoa(g.EngineElo)
+	}
+	return "Unlimited"
+}
+
+// monthOf returns "YYYY-MM" for a PGN date such as "2024.05.01".
+func monthOf(date string) (string, bool) {
+	parts := strings.Split(date, ".")
+	if len(parts) < 2 {
+		return "", false
+	}
+	if _, err := strconv.Atoi(parts[0]); err != nil || len(parts[0]) != 4 {
+		return "", false
+	}
+	if _, err := strconv.Atoi(parts[1]); err != nil || len(parts[1]) != 2 {
+		return "", false
+	}
+	return parts[0] + "-" + parts[1], true
+}
@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/RubikNube/TerminalChess/pkg/pgn"
+)
+
+const savedGames = `[Event "Casual Game"]
+[Date "2024.05.01"]
+[White "alice"]
+[Black "stockfish (Club, Elo: 1700)"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 1-0
+
+[Event "Casual Game"]
+[Date "2024.05.03"]
+[White "stockfish (Club, Elo: 1700)"]
+[Black "alice"]
+[Result "1/2-1/2"]
+[ECO "C60"]
+[Opening "Ruy Lopez"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 1/2-1/2
+
+[Event "Casual Game"]
+[Date "2024.06.10"]
+[White "alice"]
+[Black "stockfish (Expert, Elo: 2000)"]
+[Result "0-1"]
+
+1. d4 d5 0-1
+
+[Event "Casual Game"]
+[Date "2024.06.11"]
+[White "alice"]
+[Black "stockfish (Maximum, Elo: 0)"]
+[Result "*"]
+
+1. d4 *
+
+[Event "Casual Game"]
+[Date "2024.06.12"]
+[White "Carlsen, Magnus"]
+[Black "Nakamura, Hikaru"]
+[Result "1-0"]
+
+1. e4 c5 1-0
+`
+
+func readGames(t *testing.T) []Game {
+	t.Helper()
+	infos, err := pgn.ReadGames(strings.NewReader(savedGames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	games := make([]Game, len(infos))
+	for i, info := range infos {
+		games[i] = FromPGN(info)
+	}
+	return games
+}
+
+func TestFromPGN(t *testing.T) {
+	games := readGames(t)
+	g := games[1]
+	if g.Engine != "stockfish" || g.Level != "Club" || g.EngineElo != 1700 || g.Color != "black" {
+		t.Errorf("Unexpected engine game: %+v", g)
+	}
+	// Games without ECO tags are classified by their moves
+	if games[0].ECO == "" || games[0].Opening == "" {
+		t.Errorf("Expected the opening to be classified, got %+v", games[0])
+	}
+	if games[4].Engine != "" {
+		t.Errorf("Expected no engine in a game between humans, got %+v", games[4])
+	}
+	// Levels that don't limit the strength are written without an Elo and
+	// marked as a program
+	for _, tags := range [][]pgn.Tag{
+		{{Name: "White", Value: "alice"}, {Name: "Black", Value: "stockfish (Maximum)"}, {Name: "BlackType", Value: "program"}},
+		{{Name: "White", Value: "alice"}, {Name: "Black", Value: "stockfish (Maximum, Elo: 0)"}},
+	} {
+		g := FromPGN(pgn.GameInfo{Tags: tags})
+		if g.Engine != "stockfish" || g.Level != "Maximum" || g.EngineElo != 0 {
+			t.Errorf("Unexpected engine game for %v: %+v", tags, g)
+		}
+	}
+	// Players with a note in brackets are not engines
+	for _, name := range []string{"Kasparov, Garry (RUS)", "Alice (guest)"} {
+		g := FromPGN(pgn.GameInfo{Tags: []pgn.Tag{{Name: "White", Value: name}, {Name: "Black", Value: "bob"}}})
+		if g.Engine != "" {
+			t.Errorf("Expected no engine for %q, got %+v", name, g)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	r := Build(readGames(t))
+	if r.Games != 5 || r.EngineGames != 4 || r.Unfinished != 1 {
+		t.Errorf("Unexpected totals: %+v", r)
+	}
+	if len(r.Levels) != 2 || r.Levels[0].Label != "Club (1700)" || r.Levels[1].Label != "Expert (2000)" {
+		t.Fatalf("Unexpected levels: %+v", r.Levels)
+	}
+	club := r.Levels[0]
+	if club.White != (Record{Wins: 1}) || club.Black != (Record{Draws: 1}) || club.Total().Percent() != 75 {
+		t.Errorf("Unexpected club results: %+v", club)
+	}
+	if len(r.Periods) != 2 {
+		t.Fatalf("Expected two months, got %+v", r.Periods)
+	}
+	may, june := r.Periods[0], r.Periods[1]
+	if may.Period != "2024-05" || may.AverageElo != 1700 || may.Performance != 1900 {
+		t.Errorf("Unexpected May performance: %+v", may)
+	}
+	if june.Period != "2024-06" || june.Performance != 1600 {
+		t.Errorf("Unexpected June performance: %+v", june)
+	}
+	if r.Openings[0].Count != 2 || r.Openings[0].ECO[0] != 'C' {
+		t.Errorf("Expected the Ruy Lopez to be the most played opening, got %+v", r.Openings[0])
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	r := Build(readGames(t))
+	var text bytes.Buffer
+	if err := WriteText(&text, r, 1); err != nil {
+		t.Fatal(err)
+	}
+	// Only the most played opening is listed
+	if !strings.Contains(text.String(), "+1 =1 -0 (75.0%)") || !strings.Contains(text.String(), "C60") || strings.Contains(text.String(), "B20") {
+		t.Errorf("Unexpected text report:\n%s", text.String())
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV: %v", err)
+	}
+	if rows[0][0] != "section" || rows[len(rows)-1][0] != "period" || rows[len(rows)-1][9] != "1600" {
+		t.Errorf("Unexpected CSV rows: %v", rows)
+	}
+}
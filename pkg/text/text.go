@@ -0,0 +1,12 @@
+// Package text formats strings for the fixed width tables of the terminal UI
+// and the reports.
+package text
+
+// Truncate shortens s to at most n runes, ending it with "…" if it was cut.
+func Truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,21 @@
+package text
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"Carlsen", 10, "Carlsen"},
+		{"Carlsen", 7, "Carlsen"},
+		{"Carlsen, Magnus", 8, "Carlsen…"},
+		{"Großmeister", 5, "Groß…"},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.in, tt.n); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package theme
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Depth is the number of colours a terminal can show.
+type Depth int
+
+// The terminal UI draws at most 256 colours, so true colour terminals use the
+// 256 colours, with RGB values shown as the nearest of them.
+const (
+	Colors16  Depth = iota // The ANSI colours
+	Colors256              // The xterm 256-colour palette
+)
+
+// DetectDepth returns the colour depth of the terminal described by the
+// environment, as returned by os.Getenv.
+func DetectDepth(getenv func(string) string) Depth {
+	switch strings.ToLower(getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return Colors256
+	}
+	if strings.Contains(getenv("TERM"), "256color") {
+		return Colors256
+	}
+	return Colors16
+}
+
+// ParseDepth reads a colour depth given in the configuration: "16", "256" or
+// "auto", which detects it from the environment. "truecolor" is accepted as
+// 256, the most the terminal UI draws.
+func ParseDepth(s string, getenv func(string) string) (Depth, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return DetectDepth(getenv), nil
+	case "16", "8":
+		return Colors16, nil
+	case "256", "truecolor", "24bit":
+		return Colors256, nil
+	}
+	return Colors16, fmt.Errorf("unknown colour depth %q", s)
+}
+
+// colorNames are the names of the 16 ANSI colours, the bright ones prefixed
+// with "bright".
+var colorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// ansiRGB approximates the 16 ANSI colours as shown by xterm.
+var ansiRGB = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels are the channel values of the 6x6x6 colour cube of the 256 colours.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// Color is a colour of a theme: one of the 256 indexed colours or an RGB colour.
+// The zero value is the terminal's default colour.
+type Color struct {
+	set     bool
+	index   int // Index into the 256 colours, -1 for RGB colours
+	r, g, b int
+}
+
+// ParseColor reads a colour given as a name ("red", "brightblue"), an index of
+// the 256 colours ("208") or an RGB value ("#ff8700"). An empty string is the
+// default colour.
+func ParseColor(s string) (Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case s == "" || s == "default":
+		return Color{}, nil
+	case strings.HasPrefix(s, "#"):
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err != nil || len(s) != 7 {
+			return Color{}, fmt.Errorf("invalid colour %q, expected #rrggbb", s)
+		}
+		return Color{set: true, index: -1, r: int(v >> 16), g: int(v >> 8 & 0xff), b: int(v & 0xff)}, nil
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		if i < 0 || i > 255 {
+			return Color{}, fmt.Errorf("colour index %d out of range 0-255", i)
+		}
+		return Color{set: true, index: i}, nil
+	}
+	for i, name := range colorNames {
+		switch s {
+		case name:
+			return Color{set: true, index: i}, nil
+		case "bright" + name:
+			return Color{set: true, index: i + 8}, nil
+		}
+	}
+	return Color{}, fmt.Errorf("unknown colour %q", s)
+}
+
+// rgb returns the red, green and blue values of the colour.
+func (c Color) rgb() (r, g, b int) {
+	switch {
+	case c.index < 0:
+		return c.r, c.g, c.b
+	case c.index < 16:
+		v := ansiRGB[c.index]
+		return v[0], v[1], v[2]
+	case c.index < 232:
+		i := c.index - 16
+		return cubeLevels[i/36], cubeLevels[i/6%6], cubeLevels[i%6]
+	}
+	grey := 8 + 10*(c.index-232)
+	return grey, grey, grey
+}
+
+// distance returns the squared distance between two colours.
+func distance(r1, g1, b1, r2, g2, b2 int) int {
+	return (r1-r2)*(r1-r2) + (g1-g2)*(g1-g2) + (b1-b2)*(b1-b2)
+}
+
+// nearest returns the nearest of the first n indexed colours, leaving out the
+// colours except.
+func (c Color) nearest(n int, except ...int) int {
+	if c.index >= 0 && c.index < n && !slices.Contains(except, c.index) {
+		return c.index
+	}
+	r, g, b := c.rgb()
+	best, bestDistance := 0, -1
+	for i := 0; i < n; i++ {
+		if slices.Contains(except, i) {
+			continue
+		}
+		cr, cg, cb := Color{index: i}.rgb()
+		if d := distance(r, g, b, cr, cg, cb); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+// Foreground returns the escape sequence selecting the colour as text colour.
+func (c Color) Foreground(d Depth) string {
+	return c.escape(d, 30, 38)
+}
+
+// Background returns the escape sequence selecting the colour as background.
+func (c Color) Background(d Depth) string {
+	return c.escape(d, 40, 48)
+}
+
+// escape returns the escape sequence of the colour, avoiding the colours
+// except in 16-colour mode.
+func (c Color) escape(d Depth, base, extended int, except ...int) string {
+	if !c.set {
+		return ""
+	}
+	if d == Colors16 {
+		// Only the eight normal colours have their own codes in the terminal UI
+		return fmt.Sprintf("\033[%dm", base+c.nearest(8, except...))
+	}
+	return fmt.Sprintf("\033[%d;5;%dm", extended, c.nearest(256))
+}
@@ -0,0 +1,126 @@
+// Package theme holds the colour themes of the board: the colours of the
+// squares, the pieces and the highlighted squares, given as ANSI colour names,
+// indexes of the 256 colours or RGB values, and shown with as many colours as
+// the terminal supports.
+package theme
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Theme names the colours of the board. Empty colours are the terminal's
+// default colour, or no highlight for the highlighted squares.
+type Theme struct {
+	LightSquare string `json:"lightSquare"`
+	DarkSquare  string `json:"darkSquare"`
+	WhitePiece  string `json:"whitePiece"`
+	BlackPiece  string `json:"blackPiece"`
+	Cursor      string `json:"cursor"`
+	Selection   string `json:"selection"`
+	LastMove    string `json:"lastMove"` // Squares the last move came from and went to
+	Check       string `json:"check"`    // Square of a king in check
+}
+
+// Builtin are the themes that are always available. Classic is the default.
+var Builtin = map[string]Theme{
+	"classic": {
+		LightSquare: "white", DarkSquare: "black",
+		WhitePiece: "blue", BlackPiece: "red",
+		Cursor: "yellow", Selection: "green", LastMove: "cyan", Check: "magenta",
+	},
+	"wood": {
+		LightSquare: "#d7af87", DarkSquare: "#875f37",
+		WhitePiece: "#ffffff", BlackPiece: "#000000",
+		Cursor: "#ffd75f", Selection: "#87af5f", LastMove: "#afaf5f", Check: "#d75f5f",
+	},
+	"green": {
+		LightSquare: "#afd787", DarkSquare: "#5f875f",
+		WhitePiece: "#ffffff", BlackPiece: "#000000",
+		Cursor: "#ffff5f", Selection: "#d7d700", LastMove: "#afaf00", Check: "#d70000",
+	},
+	"blue": {
+		LightSquare: "#87afd7", DarkSquare: "#5f87af",
+		WhitePiece: "#ffffff", BlackPiece: "#000000",
+		Cursor: "#ffd75f", Selection: "#5fd7d7", LastMove: "#afd787", Check: "#d75f5f",
+	},
+	"contrast": {
+		LightSquare: "brightwhite", DarkSquare: "black",
+		WhitePiece: "brightyellow", BlackPiece: "brightred",
+		Cursor: "brightcyan", Selection: "brightgreen", LastMove: "blue", Check: "brightmagenta",
+	},
+}
+
+// Default is the name of the default theme.
+const Default = "classic"
+
+// Palette holds the escape sequences that select the colours of a theme: text
+// colours for the pieces, background colours for the squares.
+type Palette struct {
+	LightSquare, DarkSquare string
+	WhitePiece, BlackPiece  string
+	Cursor, Selection       string
+	LastMove, Check         string
+}
+
+// Palette returns the escape sequences of the theme's colours for a colour
+// depth. With 16 colours the pieces avoid the colours the squares are shown
+// with, as the eight colours may otherwise give both the same one.
+func (t Theme) Palette(d Depth) (Palette, error) {
+	var p Palette
+	var squares []int // Colours of the squares with 16 colours
+	for _, c := range []struct {
+		name       string
+		value      string
+		foreground bool
+		escape     *string
+	}{
+		{"lightSquare", t.LightSquare, false, &p.LightSquare},
+		{"darkSquare", t.DarkSquare, false, &p.DarkSquare},
+		{"whitePiece", t.WhitePiece, true, &p.WhitePiece},
+		{"blackPiece", t.BlackPiece, true, &p.BlackPiece},
+		{"cursor", t.Cursor, false, &p.Cursor},
+		{"selection", t.Selection, false, &p.Selection},
+		{"lastMove", t.LastMove, false, &p.LastMove},
+		{"check", t.Check, false, &p.Check},
+	} {
+		color, err := ParseColor(c.value)
+		if err != nil {
+			return Palette{}, fmt.Errorf("%s: %w", c.name, err)
+		}
+		if c.foreground {
+			*c.escape = color.escape(d, 30, 38, squares...)
+		} else {
+			*c.escape = color.Background(d)
+		}
+		if isSquare := c.escape == &p.LightSquare || c.escape == &p.DarkSquare; isSquare && color.set {
+			squares = append(squares, color.nearest(8))
+		}
+	}
+	return p, nil
+}
+
+// Names returns the sorted names of the built-in themes and the given ones.
+func Names(custom map[string]Theme) []string {
+	var names []string
+	for name := range Builtin {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, ok := Builtin[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the theme with the given name, preferring the given themes
+// over the built-in ones.
+func Lookup(name string, custom map[string]Theme) (Theme, bool) {
+	if t, ok := custom[name]; ok {
+		return t, true
+	}
+	t, ok := Builtin[name]
+	return t, ok
+}
@@ -0,0 +1,109 @@
+package theme
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in       string
+		fg16     string
+		bg256    string
+		hasError bool
+	}{
+		{"red", "\033[31m", "\033[48;5;1m", false},
+		{"brightblue", "\033[34m", "\033[48;5;12m", false},
+		{"208", "\033[33m", "\033[48;5;208m", false},
+		{"#ff8700", "\033[33m", "\033[48;5;208m", false},
+		{"#808080", "\033[33m", "\033[48;5;244m", false},
+		{"brightblack", "\033[33m", "\033[48;5;8m", false},
+		{"", "", "", false},
+		{"purple", "", "", true},
+		{"#12345", "", "", true},
+		{"256", "", "", true},
+	}
+	for _, tt := range tests {
+		c, err := ParseColor(tt.in)
+		if (err != nil) != tt.hasError {
+			t.Errorf("ParseColor(%q) error = %v", tt.in, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := c.Foreground(Colors16); got != tt.fg16 {
+			t.Errorf("%q.Foreground(16) = %q, want %q", tt.in, got, tt.fg16)
+		}
+		if got := c.Background(Colors256); got != tt.bg256 {
+			t.Errorf("%q.Background(256) = %q, want %q", tt.in, got, tt.bg256)
+		}
+	}
+}
+
+func TestDetectDepth(t *testing.T) {
+	tests := []struct {
+		colorterm, term string
+		want            Depth
+	}{
+		// The terminal UI draws true colours as the nearest of the 256 colours
+		{"truecolor", "xterm-256color", Colors256},
+		{"24bit", "xterm", Colors256},
+		{"", "screen-256color", Colors256},
+		{"", "xterm", Colors16},
+		{"", "", Colors16},
+	}
+	for _, tt := range tests {
+		env := map[string]string{"COLORTERM": tt.colorterm, "TERM": tt.term}
+		if got := DetectDepth(func(k string) string { return env[k] }); got != tt.want {
+			t.Errorf("DetectDepth(%q, %q) = %d, want %d", tt.colorterm, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestBuiltinThemes(t *testing.T) {
+	for _, name := range Names(nil) {
+		for _, d := range []Depth{Colors16, Colors256} {
+			p, err := Builtin[name].Palette(d)
+			if err != nil {
+				t.Errorf("Theme %s: %v", name, err)
+				continue
+			}
+			// Pieces must stand out from both squares and from each other
+			colors := map[string]int{
+				"light square": colorIndex(p.LightSquare), "dark square": colorIndex(p.DarkSquare),
+				"white piece": colorIndex(p.WhitePiece), "black piece": colorIndex(p.BlackPiece),
+			}
+			for _, pair := range [][2]string{
+				{"white piece", "light square"}, {"white piece", "dark square"},
+				{"black piece", "light square"}, {"black piece", "dark square"},
+				{"white piece", "black piece"}, {"light square", "dark square"},
+			} {
+				if colors[pair[0]] == colors[pair[1]] {
+					t.Errorf("Theme %s at depth %d: %s and %s are both colour %d", name, d, pair[0], pair[1], colors[pair[0]])
+				}
+			}
+		}
+	}
+	custom := map[string]Theme{"classic": {LightSquare: "bogus"}, "mine": {}}
+	if _, err := custom["classic"].Palette(Colors16); err == nil {
+		t.Error("Expected an error for an unknown colour")
+	}
+	if names := Names(custom); len(names) != len(Builtin)+1 {
+		t.Errorf("Expected the custom theme to be added once, got %v", names)
+	}
+	if theme, _ := Lookup("classic", custom); theme.LightSquare != "bogus" {
+		t.Error("Expected custom themes to override built-in ones")
+	}
+}
+
+// colorIndex returns the colour an escape sequence of a palette selects.
+func colorIndex(escape string) int {
+	params := strings.Split(strings.TrimSuffix(strings.TrimPrefix(escape, "\033["), "m"), ";")
+	n, _ := strconv.Atoi(params[len(params)-1])
+	if len(params) == 1 {
+		n %= 10 // 30-37 and 40-47
+	}
+	return n
+}